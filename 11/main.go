@@ -2,15 +2,11 @@ package main
 
 import (
 	"fmt"
-	"io/ioutil"
-	"strconv"
-	"strings"
-	"sync"
+
+	"github.com/eaceaser/advent-2019/intcode"
 )
 
 const (
-	MemSize = 64 * 1024
-
 	ColorBlack = 0
 	ColorWhite = 1
 
@@ -58,65 +54,53 @@ func (c coord) step(h heading) coord {
 }
 
 type painter struct {
-	c       *intcodeComputer
+	c       *intcode.Computer
 	pos     coord
 	heading heading
 	painted map[coord]bool
-	in      <-chan int
-	out     chan<- int
 	max     coord
 	min     coord
 }
 
 func mkPainter(mem []int) *painter {
-	in := make(chan int, 1)
-	out := make(chan int)
-
-	c := &intcodeComputer{
-		mem:    mem,
-		input:  in,
-		output: out,
-	}
-
 	return &painter{
-		c:       c,
+		c:       intcode.New("painter", mem),
 		pos:     coord{},
 		heading: 0,
 		painted: map[coord]bool{},
-		in:      out,
-		out:     in,
 	}
 }
 
 func (p *painter) run() error {
-	wg := sync.WaitGroup{}
-	wg.Add(1)
-	go func() {
-		p.out <- ColorWhite
+	in := make(chan int, 1)
+	out := make(chan int)
+
+	g := intcode.NewGroup()
+	g.Go("robot", p.c, in, out)
+	g.Func("painter", func(e *intcode.Endpoint) error {
+		color := ColorWhite
 		for {
-			i, ok := <-p.in
+			if !e.Send(in, color) {
+				return nil
+			}
+			i, ok := e.Receive(out)
 			if !ok {
-				wg.Done()
-				return
+				return nil
 			}
 			p.paint(i)
-			m := <-p.in
+			m, ok := e.Receive(out)
+			if !ok {
+				return nil
+			}
 			p.move(m)
-			color := ColorBlack
+			color = ColorBlack
 			white, ok := p.painted[p.pos]
 			if ok && white {
 				color = ColorWhite
 			}
-			p.out <- color
 		}
-	}()
-
-	err := p.c.Run()
-	if err != nil {
-		return err
-	}
-	wg.Wait()
-	return nil
+	})
+	return g.Wait()
 }
 
 func (p *painter) paint(color int) {
@@ -173,17 +157,10 @@ func (p *painter) print() {
 }
 
 func main() {
-	memS, err := ioutil.ReadFile("input")
+	mem, err := intcode.Load("input")
 	if err != nil {
 		panic(err)
 	}
-	memS1 := strings.Split(strings.TrimSpace(string(memS)), ",")
-	mem := make([]int, MemSize)
-	for i, s := range memS1 {
-		if mem[i], err = strconv.Atoi(s); err != nil {
-			panic(err)
-		}
-	}
 
 	painter := mkPainter(mem)
 	if err := painter.run(); err != nil {
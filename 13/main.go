@@ -1,19 +1,27 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"os/exec"
-	"strconv"
-	"strings"
 	"time"
+
+	"github.com/eaceaser/advent-2019/intcode"
 )
 
 const (
-	MemSize   = 1024 * 16
 	PauseTime = 250 * time.Millisecond
 
+	SnapshotFile = "arcade.snapshot"
+
+	// JournalKeep is how many journal checkpoints the game keeps for
+	// rewinding.
+	JournalKeep = 64
+	// RewindMoves is how many moves are taken back when the game is lost.
+	RewindMoves = 10
+
 	TileEmpty  tileType = 0
 	TileWall   tileType = 1
 	TileBlock  tileType = 2
@@ -40,26 +48,108 @@ func (t tileType) String() string {
 }
 
 type game struct {
-	c     *intcodeComputer
+	c     *intcode.Computer
 	t     int
 	state int
+
+	// the screen lives outside the computer, so snapshots carry it as host
+	// state
+	tiles map[point]tileType
+	ymax  int
+	score int
+}
+
+type point struct {
+	x int
+	y int
+}
+
+// screen is the form of the game's screen stored in snapshots.
+type screen struct {
+	Tiles [][3]int `json:"tiles"`
+	Score int      `json:"score"`
 }
 
-var saves []intcodeComputer
+func (g *game) draw(x int, y int, t tileType) {
+	g.tiles[point{x, y}] = t
+	if y > g.ymax {
+		g.ymax = y
+	}
+	write(x, y, t.String())
+}
+
+func (g *game) setScore(score int) {
+	g.score = score
+	write(0, g.ymax+2, fmt.Sprintf("s=%d\n", score))
+}
+
+// redraw draws the whole screen, after resuming from a snapshot.
+func (g *game) redraw() {
+	for p, t := range g.tiles {
+		g.draw(p.x, p.y, t)
+	}
+	g.setScore(g.score)
+}
+
+// save writes a snapshot of the computer and the screen.
+func (g *game) save(path string) error {
+	var scr screen
+	for p, t := range g.tiles {
+		scr.Tiles = append(scr.Tiles, [3]int{p.x, p.y, int(t)})
+	}
+	scr.Score = g.score
+	host, err := json.Marshal(scr)
+	if err != nil {
+		return err
+	}
+	snap := g.c.Snapshot()
+	snap.Host = host
+	return snap.Save(path)
+}
+
+// loadGame resumes a game saved by save.
+func loadGame(path string) (*game, error) {
+	snap, err := intcode.ReadSnapshotFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := snap.Computer()
+	if err != nil {
+		return nil, err
+	}
+	g := &game{c: c, tiles: map[point]tileType{}}
+	if len(snap.Host) > 0 {
+		var scr screen
+		if err := json.Unmarshal(snap.Host, &scr); err != nil {
+			return nil, err
+		}
+		for _, t := range scr.Tiles {
+			g.tiles[point{t[0], t[1]}] = tileType(t[2])
+		}
+		g.score = scr.Score
+	}
+	return g, nil
+}
 
 func (g *game) awaitOutput() (int, error) {
 	s, err := g.c.Run()
 	if err != nil {
 		return 0, err
 	}
-	if s != stateOutput {
+	if s != intcode.StateOutput {
 		return 0, fmt.Errorf("awaiting output state, got state %d", s)
 	}
-	return g.c.out, nil
+	return g.c.Out, nil
 }
 
 func (g *game) run() error {
-	ymax := 0
+	g.c.SetJournal(intcode.NewJournal(0, JournalKeep))
+
+	if g.c.State() == intcode.StateInput {
+		// resumed from a snapshot, which already holds the next move
+		g.redraw()
+		goto Resume
+	}
 
 	for {
 		cs, err := g.c.Run()
@@ -68,8 +158,8 @@ func (g *game) run() error {
 		}
 
 		switch cs {
-		case stateOutput:
-			x := g.c.out
+		case intcode.StateOutput:
+			x := g.c.Out
 			y, err := g.awaitOutput()
 			if err != nil {
 				return err
@@ -79,32 +169,29 @@ func (g *game) run() error {
 				return err
 			}
 
-			tt := tileType(t)
-			if y > ymax {
-				ymax = y
-			}
-			write(x, y, tt.String())
+			g.draw(x, y, tileType(t))
 
-		case stateInput:
+		case intcode.StateInput:
 			goto Running
-		case stateHalted:
+		case intcode.StateHalted:
 			return fmt.Errorf("intcode halted during setup")
 		}
 	}
 
 Running:
-	g.c.in = -1
+	g.c.In = -1
+Resume:
 	steps := 0
 	for {
-		write(0, ymax+1, fmt.Sprintf("t=%d\n", steps))
+		write(0, g.ymax+1, fmt.Sprintf("t=%d\n", steps))
 		cs, err := g.c.Run()
 		if err != nil {
 			return err
 		}
 
 		switch cs {
-		case stateOutput:
-			x := g.c.out
+		case intcode.StateOutput:
+			x := g.c.Out
 			y, err := g.awaitOutput()
 			if err != nil {
 				return err
@@ -115,61 +202,71 @@ Running:
 			}
 
 			if x == -1 && y == 0 && t != 0 {
-				write(0, ymax+2, fmt.Sprintf("s=%d\n", t))
+				g.setScore(t)
 				continue
 			}
 
-			tt := tileType(t)
-			write(x, y, tt.String())
-		case stateInput:
+			g.draw(x, y, tileType(t))
+		case intcode.StateInput:
 			var b [3]byte
+			saveNext := false
 			for {
+				b = [3]byte{}
 				os.Stdin.Read(b[:])
 				//if true {
 				if b == [3]byte{27, 91, 67} {
-					g.c.in = 1
+					g.c.In = 1
 					break
 				} else if b == [3]byte{27, 91, 68} {
-					g.c.in = -1
+					g.c.In = -1
 					break
 				} else if b == [3]byte{32, 0, 0} {
-					g.c.in = 0
+					g.c.In = 0
 					break
+				} else if b == [3]byte{115, 0, 0} {
+					// save once the next move is known, so resuming replays it
+					saveNext = true
+					write(0, g.ymax+3, "saving after next move")
 				} else if b == [3]byte{98, 0, 0} {
-					if len(saves) > 0 {
-						save := saves[0]
-						g.c = &save
-						steps--
-						break
+					if err := g.c.BackInput(); err != nil {
+						write(0, g.ymax+3, fmt.Sprintf("cannot rewind: %v", err))
+						continue
 					}
+					steps--
+					write(0, g.ymax+1, fmt.Sprintf("t=%d\n", steps))
 				} else {
-					write(0, ymax+3, fmt.Sprint("I got the byte", b, "("+string(b[:])+")"))
+					write(0, g.ymax+3, fmt.Sprint("I got the byte", b, "("+string(b[:])+")"))
+				}
+			}
+			if saveNext {
+				if err := g.save(SnapshotFile); err != nil {
+					return err
 				}
+				write(0, g.ymax+3, fmt.Sprintf("saved to %s   ", SnapshotFile))
+				saveNext = false
 			}
-			save := g.c.copy()
-			saves = append([]intcodeComputer{*save}, saves...)
 			steps++
-		case stateHalted:
+		case intcode.StateHalted:
 			goto Done
 		}
 	}
 Done:
-	save := saves[len(saves)-10]
-	saves = saves[10:]
-	g.c = &save
-	steps -= 10
+	for i := 0; i < RewindMoves; i++ {
+		if err := g.c.BackInput(); err != nil {
+			return fmt.Errorf("game over with only %d moves to rewind: %w", i, err)
+		}
+	}
+	steps -= RewindMoves
 	goto Running
 	//return nil
 }
 
 func mkGame(mem []int) *game {
-	c := &intcodeComputer{
-		name: "game",
-		mem:  mem,
-	}
+	c := intcode.New("game", mem)
 
 	return &game{
-		c: c,
+		c:     c,
+		tiles: map[point]tileType{},
 	}
 }
 
@@ -178,6 +275,42 @@ func moveCursor(x int, y int) {
 }
 
 func main() {
+	record := flag.String("record", "", "record the session's inputs and outputs to `file`")
+	replay := flag.String("replay", "", "replay a recorded session `file` headlessly and check its outputs")
+	flag.Parse()
+
+	var s *game
+	if flag.NArg() > 0 {
+		var err error
+		if s, err = loadGame(flag.Arg(0)); err != nil {
+			panic(err)
+		}
+	} else {
+		mem, err := intcode.Load("input")
+		if err != nil {
+			panic(err)
+		}
+
+		mem[0] = 2
+		s = mkGame(mem)
+	}
+
+	if *replay != "" {
+		if err := intcode.ReplayFile(s.c, *replay); err != nil {
+			panic(err)
+		}
+		fmt.Printf("%s replayed: outputs match up to step %d (%s)\n", *replay, s.c.Steps(), s.c.State())
+		return
+	}
+	if *record != "" {
+		f, err := os.Create(*record)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+		s.c.SetRecorder(intcode.NewRecorder(f))
+	}
+
 	fmt.Print("\033[H\033[2J")
 	// disable input buffering
 	exec.Command("stty", "-F", "/dev/tty", "cbreak", "min", "1").Run()
@@ -186,20 +319,6 @@ func main() {
 	// restore the echoing state when exiting
 	defer exec.Command("stty", "-F", "/dev/tty", "echo").Run()
 
-	memS, err := ioutil.ReadFile("input")
-	if err != nil {
-		panic(err)
-	}
-	memS1 := strings.Split(strings.TrimSpace(string(memS)), ",")
-	mem := make([]int, MemSize)
-	for i, s := range memS1 {
-		if mem[i], err = strconv.Atoi(s); err != nil {
-			panic(err)
-		}
-	}
-
-	mem[0] = 2
-	s := mkGame(mem)
 	if err := s.run(); err != nil {
 		panic(err)
 	}
@@ -1,12 +1,13 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"os/exec"
-	"strconv"
-	"strings"
+
+	"github.com/eaceaser/advent-2019/intcode"
 )
 
 const (
@@ -19,6 +20,14 @@ const (
 	codeWall  = 0
 	codeMove  = 1
 	codeFound = 2
+	cmdSave   = 0
+	cmdUndo   = -1
+
+	SnapshotFile = "droid.snapshot"
+
+	// JournalKeep is how many journal checkpoints manual mode keeps for
+	// undoing moves.
+	JournalKeep = 64
 
 	charDroid  = '@'
 	charWall   = '='
@@ -32,12 +41,74 @@ const (
 )
 
 type droid struct {
-	c      *intcodeComputer
+	c      *intcode.Computer
 	mode   int
 	world  []int
 	pos    int
 	steps  int
 	target int
+	// trail holds where the droid was before each manual move, for undo.
+	trail []trailStep
+}
+
+type trailStep struct {
+	pos   int
+	steps int
+}
+
+// chart is the droid's map and position, stored in snapshots alongside the
+// computer.
+type chart struct {
+	World  []int `json:"world"`
+	Pos    int   `json:"pos"`
+	Steps  int   `json:"steps"`
+	Target int   `json:"target"`
+}
+
+// save writes a snapshot of the computer and the droid's map.
+func (d *droid) save(path string) error {
+	host, err := json.Marshal(chart{World: d.world, Pos: d.pos, Steps: d.steps, Target: d.target})
+	if err != nil {
+		return err
+	}
+	snap := d.c.Snapshot()
+	snap.Host = host
+	return snap.Save(path)
+}
+
+// resume replaces the droid's computer and map with those saved in the
+// snapshot at path.
+func (d *droid) resume(path string) error {
+	snap, err := intcode.ReadSnapshotFile(path)
+	if err != nil {
+		return err
+	}
+	c, err := snap.Computer()
+	if err != nil {
+		return err
+	}
+	if len(snap.Host) > 0 {
+		var ch chart
+		if err := json.Unmarshal(snap.Host, &ch); err != nil {
+			return err
+		}
+		if len(ch.World) != len(d.world) {
+			return fmt.Errorf("snapshot map has %d cells, want %d", len(ch.World), len(d.world))
+		}
+		d.world, d.pos, d.steps, d.target = ch.World, ch.Pos, ch.Steps, ch.Target
+	}
+	d.c = c
+	return nil
+}
+
+// redraw draws every cell of the map explored so far.
+func (d *droid) redraw() {
+	for p, char := range d.world {
+		if char != 0 {
+			x, y := coord(p)
+			write(x, y, string(rune(char)))
+		}
+	}
 }
 
 func (d *droid) coord() (int, int) {
@@ -65,6 +136,32 @@ func (d *droid) nextOrientation(orientation int, tried map[int]struct{}) int {
 	return 0
 }
 
+// undo takes back the last manual move, returning the computer to the input
+// request that made it. Discovered walls stay on the map.
+func (d *droid) undo() error {
+	if len(d.trail) == 0 {
+		return intcode.ErrNoHistory
+	}
+	if err := d.c.BackInput(); err != nil {
+		return err
+	}
+	prev := d.trail[len(d.trail)-1]
+	d.trail = d.trail[:len(d.trail)-1]
+
+	var char int = charSpace
+	if d.pos == d.target {
+		char = charTarget
+	}
+	x, y := d.coord()
+	d.world[d.pos] = char
+	write(x, y, string(rune(char)))
+	d.pos, d.steps = prev.pos, prev.steps
+	d.world[d.pos] = charDroid
+	x, y = d.coord()
+	write(x, y, string(charDroid))
+	return nil
+}
+
 func (d *droid) oxygen() int {
 	d.world[d.pos] = charSpace
 	total := 0
@@ -134,6 +231,7 @@ func (d *droid) pathfind() int {
 
 func (d *droid) run() error {
 	orientation := cmdWest
+	d.redraw()
 	x, y := d.coord()
 	write(x, y, string(charDroid))
 	orig := d.pos
@@ -146,19 +244,41 @@ func (d *droid) run() error {
 
 		write(0, StatusRow, fmt.Sprintf("x=%d y=%d t=%d", x, y, d.steps))
 		switch state {
-		case stateInput:
+		case intcode.StateInput:
 			switch d.mode {
 			case modeManual:
-				d.c.in = acceptInput()
+				in := acceptInput()
+				save := false
+				for in == cmdSave || in == cmdUndo {
+					if in == cmdSave {
+						// save once the next move is known, so resuming replays it
+						save = true
+						write(0, StatusRow+1, "saving after next move")
+					} else if err := d.undo(); err != nil {
+						write(0, StatusRow+1, fmt.Sprintf("cannot undo: %v", err))
+					} else {
+						x, y := d.coord()
+						write(0, StatusRow, fmt.Sprintf("x=%d y=%d t=%d", x, y, d.steps))
+					}
+					in = acceptInput()
+				}
+				d.trail = append(d.trail, trailStep{pos: d.pos, steps: d.steps})
+				d.c.In = in
+				if save {
+					if err := d.save(SnapshotFile); err != nil {
+						return err
+					}
+					write(0, StatusRow+1, fmt.Sprintf("saved to %s   ", SnapshotFile))
+				}
 			case modeSearching:
 				next := d.nextOrientation(orientation, map[int]struct{}{})
 				if next == 0 || (d.pos == orig && d.steps > 0) {
 					d.mode = modeOxygen
-					d.c.in = cmdNorth
+					d.c.In = cmdNorth
 					continue
 				}
 				orientation = next
-				d.c.in = orientation
+				d.c.In = orientation
 			case modePathfind:
 				dist := d.pathfind()
 				write(0, StatusRow+1, fmt.Sprintf("PATH: %d\n", dist))
@@ -168,16 +288,16 @@ func (d *droid) run() error {
 				write(0, StatusRow+1, fmt.Sprintf("OXYGEN TIME: %d\n", steps))
 				return nil
 			}
-		case stateOutput:
-			switch d.c.out {
+		case intcode.StateOutput:
+			switch d.c.Out {
 			case codeWall:
 				x, y := d.coord()
-				wallX, wallY := move(x, y, d.c.in)
+				wallX, wallY := move(x, y, d.c.In)
 				d.world[pos(wallX, wallY)] = charWall
 				write(wallX, wallY, string(charWall))
 			case codeMove:
 				x, y := d.coord()
-				newX, newY := move(x, y, d.c.in)
+				newX, newY := move(x, y, d.c.In)
 				newPos := pos(newX, newY)
 				var char int
 				if d.pos == d.target {
@@ -186,14 +306,14 @@ func (d *droid) run() error {
 					char = charSpace
 				}
 				d.world[d.pos] = char
-				write(x, y, string(char))
+				write(x, y, string(rune(char)))
 				d.world[newPos] = charDroid
 				write(newX, newY, string(charDroid))
 				d.pos = newPos
 				d.steps++
 			case codeFound:
 				x, y := d.coord()
-				newX, newY := move(x, y, d.c.in)
+				newX, newY := move(x, y, d.c.In)
 				d.world[d.pos] = charSpace
 				newPos := pos(newX, newY)
 				write(x, y, string(charSpace))
@@ -219,6 +339,10 @@ func acceptInput() int {
 			return cmdNorth
 		} else if b == [3]byte{108, 0, 0} {
 			return cmdEast
+		} else if b == [3]byte{115, 0, 0} {
+			return cmdSave
+		} else if b == [3]byte{117, 0, 0} {
+			return cmdUndo
 		}
 	}
 }
@@ -233,10 +357,7 @@ func setupTTY() {
 }
 
 func mkDroid(mem []int) *droid {
-	c := &intcodeComputer{
-		name: "droid",
-		mem:  mem,
-	}
+	c := intcode.New("droid", mem)
 
 	return &droid{
 		c:     c,
@@ -247,23 +368,44 @@ func mkDroid(mem []int) *droid {
 }
 
 func main() {
-	setupTTY()
+	record := flag.String("record", "", "record the session's inputs and outputs to `file`")
+	replay := flag.String("replay", "", "replay a recorded session `file` headlessly and check its outputs")
+	flag.Parse()
 
-	inputS, err := ioutil.ReadFile("input")
+	mem, err := intcode.Load("input")
 	if err != nil {
 		panic(err)
 	}
-	cmds := strings.Split(strings.TrimSpace(string(inputS)), ",")
-	mem := make([]int, len(cmds))
-	for i, s := range cmds {
-		mem[i], err = strconv.Atoi(s)
-		if err != nil {
+
+	droid := mkDroid(mem)
+	if flag.NArg() > 0 {
+		// a resumed droid carries on under manual control
+		if err := droid.resume(flag.Arg(0)); err != nil {
 			panic(err)
 		}
+		droid.mode = modeManual
 	}
 
-	droid := mkDroid(mem)
+	if *replay != "" {
+		if err := intcode.ReplayFile(droid.c, *replay); err != nil {
+			panic(err)
+		}
+		fmt.Printf("%s replayed: outputs match up to step %d (%s)\n", *replay, droid.c.Steps(), droid.c.State())
+		return
+	}
+	if *record != "" {
+		f, err := os.Create(*record)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+		droid.c.SetRecorder(intcode.NewRecorder(f))
+	}
+	if droid.mode == modeManual {
+		droid.c.SetJournal(intcode.NewJournal(0, JournalKeep))
+	}
 
+	setupTTY()
 	if err := droid.run(); err != nil {
 		panic(err)
 	}
@@ -0,0 +1,932 @@
+// Code generated by cmd/compile from input; DO NOT EDIT.
+
+package main
+
+import (
+	"context"
+
+	"github.com/eaceaser/advent-2019/intcode"
+)
+
+// gravityImage is the program gravity was compiled from.
+var gravityImage = [...]int{
+	1, 0, 0, 3, 1, 1, 2, 3,
+	1, 3, 4, 3, 1, 5, 0, 3,
+	2, 1, 9, 19, 1, 13, 19, 23,
+	2, 23, 9, 27, 1, 6, 27, 31,
+	2, 10, 31, 35, 1, 6, 35, 39,
+	2, 9, 39, 43, 1, 5, 43, 47,
+	2, 47, 13, 51, 2, 51, 10, 55,
+	1, 55, 5, 59, 1, 59, 9, 63,
+	1, 63, 9, 67, 2, 6, 67, 71,
+	1, 5, 71, 75, 1, 75, 6, 79,
+	1, 6, 79, 83, 1, 83, 9, 87,
+	2, 87, 10, 91, 2, 91, 10, 95,
+	1, 95, 5, 99, 1, 99, 13, 103,
+	2, 103, 9, 107, 1, 6, 107, 111,
+	1, 111, 5, 115, 1, 115, 2, 119,
+	1, 5, 119, 0, 99, 2, 0, 14,
+	0,
+}
+
+// gravityCode marks the words of the compiled instructions.
+var gravityCode = [129]bool{
+	4: true, 5: true, 6: true, 7: true, 8: true, 9: true, 10: true, 11: true,
+	12: true, 13: true, 14: true, 15: true, 16: true, 17: true, 18: true, 20: true,
+	21: true, 22: true, 24: true, 25: true, 26: true, 28: true, 29: true, 30: true,
+	32: true, 33: true, 34: true, 36: true, 37: true, 38: true, 40: true, 41: true,
+	42: true, 44: true, 45: true, 46: true, 48: true, 49: true, 50: true, 52: true,
+	53: true, 54: true, 56: true, 57: true, 58: true, 60: true, 61: true, 62: true,
+	64: true, 65: true, 66: true, 68: true, 69: true, 70: true, 72: true, 73: true,
+	74: true, 76: true, 77: true, 78: true, 80: true, 81: true, 82: true, 84: true,
+	85: true, 86: true, 88: true, 89: true, 90: true, 92: true, 93: true, 94: true,
+	96: true, 97: true, 98: true, 100: true, 101: true, 102: true, 104: true, 105: true,
+	106: true, 108: true, 109: true, 110: true, 112: true, 113: true, 114: true, 116: true,
+	117: true, 118: true, 120: true, 121: true, 122: true, 123: true, 124: true,
+}
+
+// gravity runs a compiled intcode program.
+type gravity struct {
+	*intcode.Computer
+
+	// modified is set once the compiled code has been overwritten.
+	modified bool
+	// dirty is set when the interpreter has run since the code was verified.
+	dirty bool
+}
+
+// newGravity returns a machine executing mem, which should be the compiled
+// image, possibly patched at the addresses given to the compiler.
+func newGravity(name string, mem []int) *gravity {
+	return wrapGravity(intcode.New(name, mem))
+}
+
+// wrapGravity returns a machine running the compiled code on c, whose memory
+// should likewise be the compiled image. The machine takes c over.
+func wrapGravity(c *intcode.Computer) *gravity {
+	m := &gravity{Computer: c}
+	m.verify()
+	return m
+}
+
+// Copy returns an independent copy of the machine, including its memory.
+func (m *gravity) Copy() *gravity {
+	return &gravity{
+		Computer: m.Computer.Copy(),
+		modified: m.modified,
+		dirty:    m.dirty,
+	}
+}
+
+// RunChan runs the program to completion, reading input from input and
+// writing output to output. The output channel is not closed.
+func (m *gravity) RunChan(input <-chan int, output chan<- int) error {
+	for {
+		s, err := m.Run()
+		if err != nil {
+			return err
+		}
+
+		switch s {
+		case intcode.StateInput:
+			m.In = <-input
+		case intcode.StateOutput:
+			output <- m.Out
+		case intcode.StateHalted:
+			return nil
+		}
+	}
+}
+
+// RunContext is like Run, but interpreted, stopping when ctx is done.
+func (m *gravity) RunContext(ctx context.Context) (intcode.State, error) {
+	m.dirty = true
+	return m.Computer.RunContext(ctx)
+}
+
+// RunChanContext is like RunChan, but interpreted, stopping when ctx is done.
+func (m *gravity) RunChanContext(ctx context.Context, input <-chan int, output chan<- int) error {
+	m.dirty = true
+	return m.Computer.RunChanContext(ctx, input, output)
+}
+
+// Step executes a single instruction with the interpreter.
+func (m *gravity) Step() (intcode.State, error) {
+	m.dirty = true
+	return m.Computer.Step()
+}
+
+// Poke writes v to addr.
+func (m *gravity) Poke(addr int, v int) error {
+	m.dirty = true
+	return m.Computer.Poke(addr, v)
+}
+
+// Back steps the machine back one instruction.
+func (m *gravity) Back() error {
+	m.dirty = true
+	return m.Computer.Back()
+}
+
+// BackInput steps the machine back to its last input request.
+func (m *gravity) BackInput() error {
+	m.dirty = true
+	return m.Computer.BackInput()
+}
+
+// BackTo steps the machine back to the given step count.
+func (m *gravity) BackTo(step int) error {
+	m.dirty = true
+	return m.Computer.BackTo(step)
+}
+
+// verify checks that the compiled code still matches memory.
+func (m *gravity) verify() {
+	m.dirty = false
+	n := m.Native()
+	for addr, isCode := range gravityCode {
+		if v, _ := n.Load(addr); isCode && v != gravityImage[addr] {
+			m.modified = true
+			return
+		}
+	}
+}
+
+// Run executes until the program requests input, produces output, or halts,
+// like intcode.Computer.Run.
+func (m *gravity) Run() (intcode.State, error) {
+	n := m.Native()
+	if m.dirty && !m.modified {
+		m.verify()
+	}
+	if m.modified || !n.Plain() {
+		m.dirty = true
+		return m.Computer.Run()
+	}
+	if err := n.Resume(); err != nil {
+		return m.State(), err
+	}
+
+	ip, rel := n.Regs()
+	steps := 0
+	var a, b, d, p, v int
+	var ok bool
+	_, _, _, _, _, _ = a, b, d, p, v, ok
+
+	switch ip {
+	case 0:
+		goto L0
+	case 4:
+		goto L4
+	case 8:
+		goto L8
+	case 12:
+		goto L12
+	case 16:
+		goto L16
+	case 20:
+		goto L20
+	case 24:
+		goto L24
+	case 28:
+		goto L28
+	case 32:
+		goto L32
+	case 36:
+		goto L36
+	case 40:
+		goto L40
+	case 44:
+		goto L44
+	case 48:
+		goto L48
+	case 52:
+		goto L52
+	case 56:
+		goto L56
+	case 60:
+		goto L60
+	case 64:
+		goto L64
+	case 68:
+		goto L68
+	case 72:
+		goto L72
+	case 76:
+		goto L76
+	case 80:
+		goto L80
+	case 84:
+		goto L84
+	case 88:
+		goto L88
+	case 92:
+		goto L92
+	case 96:
+		goto L96
+	case 100:
+		goto L100
+	case 104:
+		goto L104
+	case 108:
+		goto L108
+	case 112:
+		goto L112
+	case 116:
+		goto L116
+	case 120:
+		goto L120
+	case 124:
+		goto L124
+	}
+	goto interp
+
+L0:
+	// 0: add [0], [0], [3]
+	if v, _ = n.Load(0); v != 1 {
+		ip = 0
+		goto interp
+	}
+	if v, _ = n.Load(3); v != 3 {
+		ip = 0
+		goto interp
+	}
+	if p, ok = n.Load(1); !ok {
+		ip = 0
+		goto interp
+	}
+	if a, ok = n.Load(p); !ok {
+		ip = 0
+		goto interp
+	}
+	if p, ok = n.Load(2); !ok {
+		ip = 0
+		goto interp
+	}
+	if b, ok = n.Load(p); !ok {
+		ip = 0
+		goto interp
+	}
+	v = a + b
+	d = 3
+	if !n.Store(d, v) {
+		ip = 0
+		goto interp
+	}
+	steps++
+
+L4:
+	// 4: add [1], [2], [3]
+	if a, ok = n.Load(1); !ok {
+		ip = 4
+		goto interp
+	}
+	if b, ok = n.Load(2); !ok {
+		ip = 4
+		goto interp
+	}
+	v = a + b
+	d = 3
+	if !n.Store(d, v) {
+		ip = 4
+		goto interp
+	}
+	steps++
+
+L8:
+	// 8: add [3], [4], [3]
+	if a, ok = n.Load(3); !ok {
+		ip = 8
+		goto interp
+	}
+	if b, ok = n.Load(4); !ok {
+		ip = 8
+		goto interp
+	}
+	v = a + b
+	d = 3
+	if !n.Store(d, v) {
+		ip = 8
+		goto interp
+	}
+	steps++
+
+L12:
+	// 12: add [5], [0], [3]
+	if a, ok = n.Load(5); !ok {
+		ip = 12
+		goto interp
+	}
+	if b, ok = n.Load(0); !ok {
+		ip = 12
+		goto interp
+	}
+	v = a + b
+	d = 3
+	if !n.Store(d, v) {
+		ip = 12
+		goto interp
+	}
+	steps++
+
+L16:
+	// 16: mul [1], [9], [19]
+	if v, _ = n.Load(19); v != 19 {
+		ip = 16
+		goto interp
+	}
+	if a, ok = n.Load(1); !ok {
+		ip = 16
+		goto interp
+	}
+	if b, ok = n.Load(9); !ok {
+		ip = 16
+		goto interp
+	}
+	v = a * b
+	d = 19
+	if !n.Store(d, v) {
+		ip = 16
+		goto interp
+	}
+	steps++
+
+L20:
+	// 20: add [13], [19], [23]
+	if v, _ = n.Load(23); v != 23 {
+		ip = 20
+		goto interp
+	}
+	if a, ok = n.Load(13); !ok {
+		ip = 20
+		goto interp
+	}
+	if b, ok = n.Load(19); !ok {
+		ip = 20
+		goto interp
+	}
+	v = a + b
+	d = 23
+	if !n.Store(d, v) {
+		ip = 20
+		goto interp
+	}
+	steps++
+
+L24:
+	// 24: mul [23], [9], [27]
+	if v, _ = n.Load(27); v != 27 {
+		ip = 24
+		goto interp
+	}
+	if a, ok = n.Load(23); !ok {
+		ip = 24
+		goto interp
+	}
+	if b, ok = n.Load(9); !ok {
+		ip = 24
+		goto interp
+	}
+	v = a * b
+	d = 27
+	if !n.Store(d, v) {
+		ip = 24
+		goto interp
+	}
+	steps++
+
+L28:
+	// 28: add [6], [27], [31]
+	if v, _ = n.Load(31); v != 31 {
+		ip = 28
+		goto interp
+	}
+	if a, ok = n.Load(6); !ok {
+		ip = 28
+		goto interp
+	}
+	if b, ok = n.Load(27); !ok {
+		ip = 28
+		goto interp
+	}
+	v = a + b
+	d = 31
+	if !n.Store(d, v) {
+		ip = 28
+		goto interp
+	}
+	steps++
+
+L32:
+	// 32: mul [10], [31], [35]
+	if v, _ = n.Load(35); v != 35 {
+		ip = 32
+		goto interp
+	}
+	if a, ok = n.Load(10); !ok {
+		ip = 32
+		goto interp
+	}
+	if b, ok = n.Load(31); !ok {
+		ip = 32
+		goto interp
+	}
+	v = a * b
+	d = 35
+	if !n.Store(d, v) {
+		ip = 32
+		goto interp
+	}
+	steps++
+
+L36:
+	// 36: add [6], [35], [39]
+	if v, _ = n.Load(39); v != 39 {
+		ip = 36
+		goto interp
+	}
+	if a, ok = n.Load(6); !ok {
+		ip = 36
+		goto interp
+	}
+	if b, ok = n.Load(35); !ok {
+		ip = 36
+		goto interp
+	}
+	v = a + b
+	d = 39
+	if !n.Store(d, v) {
+		ip = 36
+		goto interp
+	}
+	steps++
+
+L40:
+	// 40: mul [9], [39], [43]
+	if v, _ = n.Load(43); v != 43 {
+		ip = 40
+		goto interp
+	}
+	if a, ok = n.Load(9); !ok {
+		ip = 40
+		goto interp
+	}
+	if b, ok = n.Load(39); !ok {
+		ip = 40
+		goto interp
+	}
+	v = a * b
+	d = 43
+	if !n.Store(d, v) {
+		ip = 40
+		goto interp
+	}
+	steps++
+
+L44:
+	// 44: add [5], [43], [47]
+	if v, _ = n.Load(47); v != 47 {
+		ip = 44
+		goto interp
+	}
+	if a, ok = n.Load(5); !ok {
+		ip = 44
+		goto interp
+	}
+	if b, ok = n.Load(43); !ok {
+		ip = 44
+		goto interp
+	}
+	v = a + b
+	d = 47
+	if !n.Store(d, v) {
+		ip = 44
+		goto interp
+	}
+	steps++
+
+L48:
+	// 48: mul [47], [13], [51]
+	if v, _ = n.Load(51); v != 51 {
+		ip = 48
+		goto interp
+	}
+	if a, ok = n.Load(47); !ok {
+		ip = 48
+		goto interp
+	}
+	if b, ok = n.Load(13); !ok {
+		ip = 48
+		goto interp
+	}
+	v = a * b
+	d = 51
+	if !n.Store(d, v) {
+		ip = 48
+		goto interp
+	}
+	steps++
+
+L52:
+	// 52: mul [51], [10], [55]
+	if v, _ = n.Load(55); v != 55 {
+		ip = 52
+		goto interp
+	}
+	if a, ok = n.Load(51); !ok {
+		ip = 52
+		goto interp
+	}
+	if b, ok = n.Load(10); !ok {
+		ip = 52
+		goto interp
+	}
+	v = a * b
+	d = 55
+	if !n.Store(d, v) {
+		ip = 52
+		goto interp
+	}
+	steps++
+
+L56:
+	// 56: add [55], [5], [59]
+	if v, _ = n.Load(59); v != 59 {
+		ip = 56
+		goto interp
+	}
+	if a, ok = n.Load(55); !ok {
+		ip = 56
+		goto interp
+	}
+	if b, ok = n.Load(5); !ok {
+		ip = 56
+		goto interp
+	}
+	v = a + b
+	d = 59
+	if !n.Store(d, v) {
+		ip = 56
+		goto interp
+	}
+	steps++
+
+L60:
+	// 60: add [59], [9], [63]
+	if v, _ = n.Load(63); v != 63 {
+		ip = 60
+		goto interp
+	}
+	if a, ok = n.Load(59); !ok {
+		ip = 60
+		goto interp
+	}
+	if b, ok = n.Load(9); !ok {
+		ip = 60
+		goto interp
+	}
+	v = a + b
+	d = 63
+	if !n.Store(d, v) {
+		ip = 60
+		goto interp
+	}
+	steps++
+
+L64:
+	// 64: add [63], [9], [67]
+	if v, _ = n.Load(67); v != 67 {
+		ip = 64
+		goto interp
+	}
+	if a, ok = n.Load(63); !ok {
+		ip = 64
+		goto interp
+	}
+	if b, ok = n.Load(9); !ok {
+		ip = 64
+		goto interp
+	}
+	v = a + b
+	d = 67
+	if !n.Store(d, v) {
+		ip = 64
+		goto interp
+	}
+	steps++
+
+L68:
+	// 68: mul [6], [67], [71]
+	if v, _ = n.Load(71); v != 71 {
+		ip = 68
+		goto interp
+	}
+	if a, ok = n.Load(6); !ok {
+		ip = 68
+		goto interp
+	}
+	if b, ok = n.Load(67); !ok {
+		ip = 68
+		goto interp
+	}
+	v = a * b
+	d = 71
+	if !n.Store(d, v) {
+		ip = 68
+		goto interp
+	}
+	steps++
+
+L72:
+	// 72: add [5], [71], [75]
+	if v, _ = n.Load(75); v != 75 {
+		ip = 72
+		goto interp
+	}
+	if a, ok = n.Load(5); !ok {
+		ip = 72
+		goto interp
+	}
+	if b, ok = n.Load(71); !ok {
+		ip = 72
+		goto interp
+	}
+	v = a + b
+	d = 75
+	if !n.Store(d, v) {
+		ip = 72
+		goto interp
+	}
+	steps++
+
+L76:
+	// 76: add [75], [6], [79]
+	if v, _ = n.Load(79); v != 79 {
+		ip = 76
+		goto interp
+	}
+	if a, ok = n.Load(75); !ok {
+		ip = 76
+		goto interp
+	}
+	if b, ok = n.Load(6); !ok {
+		ip = 76
+		goto interp
+	}
+	v = a + b
+	d = 79
+	if !n.Store(d, v) {
+		ip = 76
+		goto interp
+	}
+	steps++
+
+L80:
+	// 80: add [6], [79], [83]
+	if v, _ = n.Load(83); v != 83 {
+		ip = 80
+		goto interp
+	}
+	if a, ok = n.Load(6); !ok {
+		ip = 80
+		goto interp
+	}
+	if b, ok = n.Load(79); !ok {
+		ip = 80
+		goto interp
+	}
+	v = a + b
+	d = 83
+	if !n.Store(d, v) {
+		ip = 80
+		goto interp
+	}
+	steps++
+
+L84:
+	// 84: add [83], [9], [87]
+	if v, _ = n.Load(87); v != 87 {
+		ip = 84
+		goto interp
+	}
+	if a, ok = n.Load(83); !ok {
+		ip = 84
+		goto interp
+	}
+	if b, ok = n.Load(9); !ok {
+		ip = 84
+		goto interp
+	}
+	v = a + b
+	d = 87
+	if !n.Store(d, v) {
+		ip = 84
+		goto interp
+	}
+	steps++
+
+L88:
+	// 88: mul [87], [10], [91]
+	if v, _ = n.Load(91); v != 91 {
+		ip = 88
+		goto interp
+	}
+	if a, ok = n.Load(87); !ok {
+		ip = 88
+		goto interp
+	}
+	if b, ok = n.Load(10); !ok {
+		ip = 88
+		goto interp
+	}
+	v = a * b
+	d = 91
+	if !n.Store(d, v) {
+		ip = 88
+		goto interp
+	}
+	steps++
+
+L92:
+	// 92: mul [91], [10], [95]
+	if v, _ = n.Load(95); v != 95 {
+		ip = 92
+		goto interp
+	}
+	if a, ok = n.Load(91); !ok {
+		ip = 92
+		goto interp
+	}
+	if b, ok = n.Load(10); !ok {
+		ip = 92
+		goto interp
+	}
+	v = a * b
+	d = 95
+	if !n.Store(d, v) {
+		ip = 92
+		goto interp
+	}
+	steps++
+
+L96:
+	// 96: add [95], [5], [99]
+	if v, _ = n.Load(99); v != 99 {
+		ip = 96
+		goto interp
+	}
+	if a, ok = n.Load(95); !ok {
+		ip = 96
+		goto interp
+	}
+	if b, ok = n.Load(5); !ok {
+		ip = 96
+		goto interp
+	}
+	v = a + b
+	d = 99
+	if !n.Store(d, v) {
+		ip = 96
+		goto interp
+	}
+	steps++
+
+L100:
+	// 100: add [99], [13], [103]
+	if v, _ = n.Load(103); v != 103 {
+		ip = 100
+		goto interp
+	}
+	if a, ok = n.Load(99); !ok {
+		ip = 100
+		goto interp
+	}
+	if b, ok = n.Load(13); !ok {
+		ip = 100
+		goto interp
+	}
+	v = a + b
+	d = 103
+	if !n.Store(d, v) {
+		ip = 100
+		goto interp
+	}
+	steps++
+
+L104:
+	// 104: mul [103], [9], [107]
+	if v, _ = n.Load(107); v != 107 {
+		ip = 104
+		goto interp
+	}
+	if a, ok = n.Load(103); !ok {
+		ip = 104
+		goto interp
+	}
+	if b, ok = n.Load(9); !ok {
+		ip = 104
+		goto interp
+	}
+	v = a * b
+	d = 107
+	if !n.Store(d, v) {
+		ip = 104
+		goto interp
+	}
+	steps++
+
+L108:
+	// 108: add [6], [107], [111]
+	if v, _ = n.Load(111); v != 111 {
+		ip = 108
+		goto interp
+	}
+	if a, ok = n.Load(6); !ok {
+		ip = 108
+		goto interp
+	}
+	if b, ok = n.Load(107); !ok {
+		ip = 108
+		goto interp
+	}
+	v = a + b
+	d = 111
+	if !n.Store(d, v) {
+		ip = 108
+		goto interp
+	}
+	steps++
+
+L112:
+	// 112: add [111], [5], [115]
+	if v, _ = n.Load(115); v != 115 {
+		ip = 112
+		goto interp
+	}
+	if a, ok = n.Load(111); !ok {
+		ip = 112
+		goto interp
+	}
+	if b, ok = n.Load(5); !ok {
+		ip = 112
+		goto interp
+	}
+	v = a + b
+	d = 115
+	if !n.Store(d, v) {
+		ip = 112
+		goto interp
+	}
+	steps++
+
+L116:
+	// 116: add [115], [2], [119]
+	if v, _ = n.Load(119); v != 119 {
+		ip = 116
+		goto interp
+	}
+	if a, ok = n.Load(115); !ok {
+		ip = 116
+		goto interp
+	}
+	if b, ok = n.Load(2); !ok {
+		ip = 116
+		goto interp
+	}
+	v = a + b
+	d = 119
+	if !n.Store(d, v) {
+		ip = 116
+		goto interp
+	}
+	steps++
+
+L120:
+	// 120: add [5], [119], [0]
+	if a, ok = n.Load(5); !ok {
+		ip = 120
+		goto interp
+	}
+	if b, ok = n.Load(119); !ok {
+		ip = 120
+		goto interp
+	}
+	v = a + b
+	d = 0
+	if !n.Store(d, v) {
+		ip = 120
+		goto interp
+	}
+	steps++
+
+L124:
+	// 124: hlt
+	steps++
+	n.Yield(intcode.StateHalted, 124, 125, rel, 0, steps)
+	return intcode.StateHalted, nil
+
+interp:
+	m.dirty = true
+	n.Yield(intcode.StateRunning, ip, ip, rel, 0, steps)
+	return m.Computer.Run()
+}
@@ -1,109 +1,208 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
-	"io/ioutil"
-	"os"
 	"strconv"
 	"strings"
+
+	"github.com/eaceaser/advent-2019/intcode"
 )
 
+//go:generate go run ../cmd/compile -type gravity -patch 1,2 -o compiled.go input
+
 const (
-	OpcodeAdd      = 1
-	OpcodeMultiply = 2
-	OpcodeDie      = 99
-	Target         = 19690720
+	Target = 19690720
 )
 
-type IntcodeComputer struct {
-	mem []int
-	ip  int
+// variable is a memory cell whose initial value is searched for.
+type variable struct {
+	addr int
+	lo   int
+	hi   int
 }
 
-func (c *IntcodeComputer) add() {
-	a := c.read()
-	b := c.read()
-	x := c.read()
-
-	c.mem[x] = c.mem[a] + c.mem[b]
+// parseVars parses comma separated addr=lo..hi ranges.
+func parseVars(s string) ([]variable, error) {
+	var rv []variable
+	for _, p := range strings.Split(s, ",") {
+		var v variable
+		kv := strings.SplitN(strings.TrimSpace(p), "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("bad variable %q", p)
+		}
+		bounds := strings.SplitN(kv[1], "..", 2)
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("bad range %q", kv[1])
+		}
+		var err error
+		if v.addr, err = strconv.Atoi(kv[0]); err != nil {
+			return nil, err
+		}
+		if v.lo, err = strconv.Atoi(bounds[0]); err != nil {
+			return nil, err
+		}
+		if v.hi, err = strconv.Atoi(bounds[1]); err != nil {
+			return nil, err
+		}
+		rv = append(rv, v)
+	}
+	return rv, nil
 }
 
-func (c *IntcodeComputer) multiply() {
-	a := c.read()
-	b := c.read()
-	x := c.read()
+func prep(mem []int, vars []variable, vals []int) {
+	for i, v := range vars {
+		mem[v.addr] = vals[i]
+	}
+}
 
-	c.mem[x] = c.mem[a] * c.mem[b]
+func run(mem []int) (int, error) {
+	return runGravity(newGravity("gravity", mem))
 }
 
-func (c *IntcodeComputer) Run() (int, error) {
+func runGravity(c *gravity) (int, error) {
 	for {
-		opcode := c.read()
-		switch opcode {
-		case OpcodeAdd:
-			c.add()
-		case OpcodeMultiply:
-			c.multiply()
-		case OpcodeDie:
-			return c.mem[0], nil
-		default:
-			return 0, fmt.Errorf("illegal opcode %d", opcode)
+		s, err := c.Run()
+		if err != nil {
+			return 0, err
+		}
+		if s == intcode.StateHalted {
+			return c.Peek(0), nil
 		}
 	}
 }
 
-func (c *IntcodeComputer) read() int {
-	rv := c.mem[c.ip]
-	c.ip++
-	return rv
+// solveLinear solves expr = target over the variables' ranges. One variable
+// with a nonzero coefficient is solved for directly; the others are
+// enumerated.
+func solveLinear(expr intcode.Linear, vars []variable, target int) ([]int, bool) {
+	solved := -1
+	for i, v := range vars {
+		if expr.Coef[v.addr] != 0 {
+			solved = i
+		}
+	}
+
+	vals := make([]int, len(vars))
+	if solved < 0 {
+		for i, v := range vars {
+			vals[i] = v.lo
+		}
+		return vals, expr.Const == target
+	}
+
+	v, k := vars[solved], expr.Coef[vars[solved].addr]
+	found := false
+	var solve func(i int, rest int) bool
+	solve = func(i int, rest int) bool {
+		if i < len(vars) && i != solved {
+			for vals[i] = vars[i].lo; vals[i] <= vars[i].hi; vals[i]++ {
+				if solve(i+1, rest-expr.Coef[vars[i].addr]*vals[i]) {
+					return true
+				}
+			}
+			return false
+		}
+		if i < len(vars) {
+			return solve(i+1, rest)
+		}
+		if rest%k != 0 || rest/k < v.lo || rest/k > v.hi {
+			return false
+		}
+		vals[solved] = rest / k
+		found = true
+		return true
+	}
+	solve(0, target-expr.Const)
+	return vals, found
 }
 
-func prep(mem []int, a, b int) {
-	mem[1] = a
-	mem[2] = b
+// search tries every combination of values on all CPUs.
+func search(mem []int, vars []variable, target int) ([]int, bool, error) {
+	lo := make([]int, len(vars))
+	hi := make([]int, len(vars))
+	for i, v := range vars {
+		lo[i], hi[i] = v.lo, v.hi
+	}
+	n, cand := intcode.Product(lo, hi)
+	base := intcode.New("gravity", mem)
+	return intcode.FindFirst(base, n, cand, func(c *intcode.Computer, vals []int) (bool, error) {
+		for i, v := range vars {
+			if err := c.Poke(v.addr, vals[i]); err != nil {
+				return false, err
+			}
+		}
+		res, err := runGravity(wrapGravity(c))
+		return res == target, err
+	})
+}
+
+// solve finds values for vars that make the program leave target at address
+// 0. It solves symbolically unless brute is set or the program is not linear
+// in the variables, in which case it searches.
+func solve(mem []int, vars []variable, target int, brute bool) ([]int, bool, error) {
+	if !brute {
+		addrs := make([]int, len(vars))
+		for i, v := range vars {
+			addrs[i] = v.addr
+		}
+		expr, err := intcode.EvalLinear(mem, addrs, 0)
+		switch {
+		case err == nil:
+			fmt.Printf("[0] = %s\n", expr)
+			vals, found := solveLinear(expr, vars, target)
+			return vals, found, nil
+		case errors.Is(err, intcode.ErrNotLinear):
+			fmt.Printf("linear solver failed, searching: %v\n", err)
+		default:
+			return nil, false, err
+		}
+	}
+	return search(mem, vars, target)
 }
 
 func main() {
-	file, err := os.Open("input")
+	target := flag.Int("target", Target, "value the program should leave at address 0")
+	varSpec := flag.String("vars", "1=0..99,2=0..99", "comma separated `addr=lo..hi` cells to solve for")
+	brute := flag.Bool("search", false, "skip the linear solver and search every combination")
+	flag.Parse()
+
+	path := "input"
+	if flag.NArg() > 0 {
+		path = flag.Arg(0)
+	}
+
+	mem, err := intcode.Load(path)
 	if err != nil {
 		panic(err)
 	}
-	defer file.Close()
-
-	memS, err := ioutil.ReadAll(file)
+	vars, err := parseVars(*varSpec)
 	if err != nil {
 		panic(err)
 	}
 
-	memS1 := strings.Split(string(memS), ",")
-	mem := make([]int, len(memS1))
-	for i, m := range memS1 {
-		mi, err := strconv.Atoi(strings.TrimSpace(m))
-		if err != nil {
-			panic(err)
-		}
-		mem[i] = mi
+	vals, found, err := solve(mem, vars, *target, *brute)
+	if err != nil {
+		panic(err)
+	}
+	if !found {
+		fmt.Println("no solution")
+		return
 	}
 
-	prep(mem, 12, 2)
-
-	for i := 0; i < 100; i++ {
-		for j := 0; j < 100; j++ {
-			mem2 := make([]int, len(mem))
-			copy(mem2, mem)
-			prep(mem2, i, j)
-			c := IntcodeComputer{
-				mem: mem2,
-			}
-			res, err := c.Run()
-			if err != nil {
-				panic(err)
-			}
+	cmem := append([]int(nil), mem...)
+	prep(cmem, vars, vals)
+	if res, err := run(cmem); err != nil || res != *target {
+		panic(fmt.Sprintf("solution %v gives %d, %v", vals, res, err))
+	}
 
-			if res == Target {
-				fmt.Printf("noun=%d verb=%d answer=%d\n", i, j, 100*i+j)
-				return
-			}
-		}
+	parts := make([]string, len(vars))
+	for i, v := range vars {
+		parts[i] = fmt.Sprintf("[%d]=%d", v.addr, vals[i])
+	}
+	fmt.Println(strings.Join(parts, " "))
+	if len(vals) == 2 {
+		fmt.Printf("answer=%d\n", 100*vals[0]+vals[1])
 	}
 }
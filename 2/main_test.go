@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/eaceaser/advent-2019/intcode"
+)
+
+func TestSolve(t *testing.T) {
+	mem, err := intcode.Load("input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	day2 := []variable{{addr: 1, lo: 0, hi: 99}, {addr: 2, lo: 0, hi: 99}}
+	tests := []struct {
+		name   string
+		mem    []int
+		vars   []variable
+		target int
+		brute  bool
+		want   []int
+	}{
+		{name: "linear", mem: mem, vars: day2, target: Target, want: []int{93, 42}},
+		{name: "search", mem: mem, vars: day2, target: Target, brute: true, want: []int{93, 42}},
+		// [0] = [noun] * [verb] cannot be solved symbolically, so it is
+		// searched for
+		{name: "not-linear", mem: []int{2, 0, 0, 0, 99},
+			vars:   []variable{{addr: 1, lo: 0, hi: 4}, {addr: 2, lo: 0, hi: 4}},
+			target: 9801, want: []int{4, 4}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			vals, found, err := solve(tc.mem, tc.vars, tc.target, tc.brute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !found || !reflect.DeepEqual(vals, tc.want) {
+				t.Errorf("solve = %v, %t, want %v", vals, found, tc.want)
+			}
+		})
+	}
+}
@@ -2,58 +2,33 @@ package main
 
 import (
 	"fmt"
-	"io/ioutil"
-	"strconv"
-	"strings"
-	"sync"
-)
 
-const (
-	MemSize = 64 * 1024
+	"github.com/eaceaser/advent-2019/intcode"
 )
 
 func main() {
-	memS, err := ioutil.ReadFile("input")
+	mem, err := intcode.Load("input")
 	if err != nil {
 		panic(err)
 	}
 
-	memS1 := strings.Split(strings.TrimSpace(string(memS)), ",")
-	mem := make([]int, MemSize)
-	for i, s := range memS1 {
-		mem[i], err = strconv.Atoi(s)
-		if err != nil {
-			panic(err)
-		}
-	}
-
-	input := make(chan int, 1)
-	input <- 2
 	output := make(chan int)
-	c := intcodeComputer{
-		mem:    mem,
-		input:  input,
-		output: output,
-	}
+	c := intcode.New("boost", mem)
+	c.SetArith(intcode.ArithChecked)
 
-	wait := sync.WaitGroup{}
-	wait.Add(1)
-	go func() {
+	g := intcode.NewGroup()
+	g.Go("boost", c, nil, output, 2)
+	g.Func("printer", func(e *intcode.Endpoint) error {
 		for {
-			select {
-			case o, ok := <-output:
-				if !ok {
-					wait.Done()
-					return
-				}
-				fmt.Printf("[OUT] %d\n", o)
+			o, ok := e.Receive(output)
+			if !ok {
+				return nil
 			}
+			fmt.Printf("[OUT] %d\n", o)
 		}
-	}()
+	})
 
-	if err := c.Run(); err != nil {
+	if err := g.Wait(); err != nil {
 		panic(err)
 	}
-	close(output)
-	wait.Wait()
 }
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/eaceaser/advent-2019/intcode"
+)
+
+func main() {
+	var src []byte
+	var err error
+	if len(os.Args) > 1 {
+		src, err = ioutil.ReadFile(os.Args[1])
+	} else {
+		src, err = ioutil.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	mem, err := intcode.Assemble(string(src))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	words := make([]string, len(mem))
+	for i, w := range mem {
+		words[i] = strconv.Itoa(w)
+	}
+	fmt.Println(strings.Join(words, ","))
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/eaceaser/advent-2019/intcode"
+)
+
+func main() {
+	subs := flag.Bool("subs", false, "list subroutines instead of writing the graph")
+	flag.Parse()
+
+	path := "input"
+	if flag.NArg() > 0 {
+		path = flag.Arg(0)
+	}
+
+	mem, err := intcode.Load(path)
+	if err != nil {
+		panic(err)
+	}
+
+	g := intcode.BuildCFG(mem)
+	if !*subs {
+		if err := g.WriteDOT(os.Stdout); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	callers := map[int]int{}
+	for _, b := range g.Blocks {
+		if b.Call >= 0 {
+			callers[b.Call]++
+		}
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	for addr := 0; addr < len(mem); addr++ {
+		blocks, ok := g.Subs[addr]
+		if !ok {
+			continue
+		}
+		insts := 0
+		for _, start := range blocks {
+			insts += len(g.Blocks[start].Insts)
+		}
+		fmt.Fprintf(w, "%-10s at %-6d %4d blocks %5d instructions %3d callers\n",
+			intcode.SubName(addr), addr, len(blocks), insts, callers[addr])
+	}
+	if err := w.Flush(); err != nil {
+		panic(err)
+	}
+}
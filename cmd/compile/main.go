@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/eaceaser/advent-2019/intcode"
+)
+
+func parseList(s string) ([]int, error) {
+	var rv []int
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, err
+		}
+		rv = append(rv, v)
+	}
+	return rv, nil
+}
+
+func main() {
+	pkg := flag.String("pkg", "main", "package `name` of the generated file")
+	typ := flag.String("type", "program", "`name` of the generated machine type")
+	patches := flag.String("patch", "", "comma separated addresses patched before the program runs")
+	out := flag.String("o", "", "write the generated code to `file` instead of stdout")
+	flag.Parse()
+
+	path := "input"
+	if flag.NArg() > 0 {
+		path = flag.Arg(0)
+	}
+
+	mem, err := intcode.Load(path)
+	if err != nil {
+		panic(err)
+	}
+	patch, err := parseList(*patches)
+	if err != nil {
+		panic(err)
+	}
+
+	var buf bytes.Buffer
+	err = intcode.Compile(&buf, mem, intcode.CompileOptions{
+		Package: *pkg,
+		Type:    *typ,
+		Patch:   patch,
+		Source:  path,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	if *out == "" {
+		if _, err := os.Stdout.Write(buf.Bytes()); err != nil {
+			panic(err)
+		}
+		return
+	}
+	if err := ioutil.WriteFile(*out, buf.Bytes(), 0644); err != nil {
+		panic(err)
+	}
+}
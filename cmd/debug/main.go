@@ -0,0 +1,451 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eaceaser/advent-2019/intcode"
+)
+
+const (
+	listBefore = 3
+	listAfter  = 6
+
+	// journalKeep bounds the history kept for stepping backward.
+	journalKeep = 256
+)
+
+const help = `commands:
+  s [n]              step n instructions (default 1)
+  bs [n]             step back n instructions (default 1)
+  bi                 step back to the last input request; its value must be queued again
+  c                  continue until a breakpoint, watchpoint, missing input or halt
+  b <addr>           break when ip reaches addr
+  bo <op>            break before executing an opcode (number or mnemonic)
+  w <addr>           stop when the value at addr changes
+  d <addr>           delete the breakpoint or watchpoint at addr
+  do <op>            delete an opcode breakpoint
+  info               list breakpoints and watchpoints
+  r                  print ip, rel, state and pending i/o
+  p <addr> [n]       print n memory cells starting at addr
+  set <addr> <v>     write v to addr; history before it is forgotten
+  set ip|rel <v>     set the instruction pointer or relative base, likewise
+  in <v> [v...]      queue input values
+  l [addr]           disassemble around addr (default ip)
+  reset              restart the program, keeping breakpoints and watchpoints
+  q                  quit`
+
+type debugger struct {
+	c       *intcode.Computer
+	mem     []int
+	fault   error
+	breaks  map[int]bool
+	opBreak map[int]bool
+	watches map[int]int
+	inputs  []int
+	outputs []int
+}
+
+func mkDebugger(mem []int) *debugger {
+	d := &debugger{
+		c:       intcode.New("debug", mem),
+		mem:     mem,
+		breaks:  map[int]bool{},
+		opBreak: map[int]bool{},
+		watches: map[int]int{},
+	}
+	d.resetJournal()
+	return d
+}
+
+// resetJournal starts recording history afresh. Changes made by hand are not
+// journaled, so history from before them cannot be replayed.
+func (d *debugger) resetJournal() {
+	d.c.SetJournal(intcode.NewJournal(0, journalKeep))
+}
+
+// back steps backward with f and resyncs the watchpoints, which would
+// otherwise fire on the next step.
+func (d *debugger) back(f func() error) error {
+	if err := f(); err != nil {
+		return err
+	}
+	d.fault = nil
+	for addr := range d.watches {
+		d.watches[addr] = d.c.Peek(addr)
+	}
+	d.list(d.pc(), 0, 1)
+	return nil
+}
+
+// step executes one instruction. It reports false when execution cannot
+// continue: the program halted, errored, or needs input that is not queued.
+func (d *debugger) step() bool {
+	if d.fault != nil {
+		fmt.Println("program faulted; reset or step back first")
+		return false
+	}
+	switch d.c.State() {
+	case intcode.StateHalted:
+		fmt.Println("program halted")
+		return false
+	case intcode.StateInput:
+		if len(d.inputs) == 0 {
+			fmt.Println("waiting for input; queue some with `in`")
+			return false
+		}
+		d.c.In = d.inputs[0]
+		d.inputs = d.inputs[1:]
+	}
+
+	s, err := d.c.Step()
+	if err != nil {
+		d.fault = err
+		fmt.Printf("error at ip=%d: %v\n", d.pc(), err)
+		return false
+	}
+
+	switch s {
+	case intcode.StateOutput:
+		d.outputs = append(d.outputs, d.c.Out)
+		fmt.Printf("[OUT] %d\n", d.c.Out)
+	case intcode.StateHalted:
+		fmt.Println("program halted")
+		return false
+	}
+
+	return !d.checkWatches()
+}
+
+// pc returns the address of the current instruction. After a fault that is
+// where the faulting instruction starts, since the VM leaves the instruction
+// pointer part way through it.
+func (d *debugger) pc() int {
+	if f, ok := intcode.FaultOf(d.fault); ok {
+		return f.IP
+	}
+	return d.c.IP()
+}
+
+func (d *debugger) checkWatches() bool {
+	hit := false
+	for _, addr := range sortedKeys(d.watches) {
+		old := d.watches[addr]
+		if v := d.c.Peek(addr); v != old {
+			fmt.Printf("watch [%d]: %d -> %d\n", addr, old, v)
+			d.watches[addr] = v
+			hit = true
+		}
+	}
+	return hit
+}
+
+func (d *debugger) atBreak() bool {
+	ip := d.c.IP()
+	if d.breaks[ip] {
+		fmt.Printf("breakpoint at %d\n", ip)
+		return true
+	}
+	op, _ := intcode.ParseOpcode(d.c.Peek(ip))
+	if d.opBreak[op] {
+		fmt.Printf("opcode breakpoint %d at %d\n", op, ip)
+		return true
+	}
+	return false
+}
+
+func (d *debugger) cont() {
+	for first := true; ; first = false {
+		if !first && d.atBreak() {
+			break
+		}
+		if !d.step() {
+			break
+		}
+	}
+	d.list(d.pc(), 0, 1)
+}
+
+func (d *debugger) list(addr int, before int, after int) {
+	// instructions are variable length, so find a start point by decoding
+	// forward from a few words back and keeping the path that lands on addr
+	start := addr
+	for back := before; back > 0; back-- {
+		for off := 1; off <= 4; off++ {
+			if inst, ok := d.c.Decode(start - off); ok && start-off+inst.Len() == start {
+				start -= off
+				break
+			}
+		}
+	}
+
+	for a, n := start, 0; n < before+after; n++ {
+		marker := "  "
+		if a == d.pc() {
+			marker = "=>"
+		}
+		if d.breaks[a] {
+			marker = "*" + marker[1:]
+		}
+		inst, ok := d.c.Decode(a)
+		if !ok {
+			fmt.Printf("%s %6d  .data %d\n", marker, a, d.c.Peek(a))
+			a++
+			continue
+		}
+		words := make([]string, inst.Len())
+		for i := range words {
+			words[i] = strconv.Itoa(d.c.Peek(a + i))
+		}
+		fmt.Printf("%s %6d  %-24s %s\n", marker, a, strings.Join(words, " "), inst)
+		a += inst.Len()
+	}
+}
+
+func (d *debugger) regs() {
+	fmt.Printf("ip=%d rel=%d state=%s\n", d.pc(), d.c.Rel(), d.c.State())
+	if d.fault != nil {
+		fmt.Printf("faulted: %v\n", d.fault)
+	}
+	if tp, ok := d.c.InputTarget(); ok {
+		fmt.Printf("pending input -> [%d]\n", tp)
+	}
+	if d.c.State() == intcode.StateOutput {
+		fmt.Printf("last output %d\n", d.c.Out)
+	}
+	fmt.Printf("queued input %v\n", d.inputs)
+}
+
+func (d *debugger) info() {
+	for _, addr := range sortedKeys(d.breaks) {
+		fmt.Printf("break %d\n", addr)
+	}
+	for _, op := range sortedKeys(d.opBreak) {
+		fmt.Printf("break opcode %d\n", op)
+	}
+	for _, addr := range sortedKeys(d.watches) {
+		fmt.Printf("watch [%d] = %d\n", addr, d.watches[addr])
+	}
+}
+
+func (d *debugger) exec(args []string) error {
+	switch args[0] {
+	case "s", "step":
+		n := 1
+		if len(args) > 1 {
+			var err error
+			if n, err = intArg(args, 1); err != nil {
+				return err
+			}
+		}
+		for i := 0; i < n; i++ {
+			if !d.step() {
+				break
+			}
+		}
+		d.list(d.pc(), 0, 1)
+	case "bs":
+		n := 1
+		if len(args) > 1 {
+			var err error
+			if n, err = intArg(args, 1); err != nil {
+				return err
+			}
+		}
+		target := d.c.Steps() - n
+		if d.fault != nil {
+			// the faulting instruction was not counted, so the first step
+			// back returns to its start
+			target++
+		}
+		return d.back(func() error { return d.c.BackTo(target) })
+	case "bi":
+		return d.back(d.c.BackInput)
+	case "c", "continue":
+		d.cont()
+	case "b", "break":
+		addr, err := intArg(args, 1)
+		if err != nil {
+			return err
+		}
+		d.breaks[addr] = true
+	case "bo":
+		op, err := opArg(args, 1)
+		if err != nil {
+			return err
+		}
+		d.opBreak[op] = true
+	case "w", "watch":
+		addr, err := intArg(args, 1)
+		if err != nil {
+			return err
+		}
+		d.watches[addr] = d.c.Peek(addr)
+	case "d", "delete":
+		addr, err := intArg(args, 1)
+		if err != nil {
+			return err
+		}
+		delete(d.breaks, addr)
+		delete(d.watches, addr)
+	case "do":
+		op, err := opArg(args, 1)
+		if err != nil {
+			return err
+		}
+		delete(d.opBreak, op)
+	case "info":
+		d.info()
+	case "r", "regs":
+		d.regs()
+	case "p", "print":
+		addr, err := intArg(args, 1)
+		if err != nil {
+			return err
+		}
+		n := 1
+		if len(args) > 2 {
+			if n, err = intArg(args, 2); err != nil {
+				return err
+			}
+		}
+		for i := 0; i < n; i++ {
+			fmt.Printf("[%d] = %d\n", addr+i, d.c.Peek(addr+i))
+		}
+	case "set":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: set <addr|ip|rel> <value>")
+		}
+		v, err := intArg(args, 2)
+		if err != nil {
+			return err
+		}
+		switch args[1] {
+		case "ip":
+			d.c.SetIP(v)
+		case "rel":
+			d.c.SetRel(v)
+		default:
+			addr, err := intArg(args, 1)
+			if err != nil {
+				return err
+			}
+			if err := d.c.Poke(addr, v); err != nil {
+				return err
+			}
+			if _, ok := d.watches[addr]; ok {
+				d.watches[addr] = v
+			}
+		}
+		d.resetJournal()
+	case "in", "input":
+		for i := 1; i < len(args); i++ {
+			v, err := intArg(args, i)
+			if err != nil {
+				return err
+			}
+			d.inputs = append(d.inputs, v)
+		}
+	case "l", "list":
+		addr := d.pc()
+		if len(args) > 1 {
+			var err error
+			if addr, err = intArg(args, 1); err != nil {
+				return err
+			}
+		}
+		d.list(addr, listBefore, listAfter)
+	case "reset":
+		d.c = intcode.New("debug", d.mem)
+		d.fault = nil
+		d.inputs = nil
+		d.outputs = nil
+		d.resetJournal()
+		for addr := range d.watches {
+			d.watches[addr] = d.c.Peek(addr)
+		}
+		d.list(d.pc(), 0, 1)
+	case "h", "help":
+		fmt.Println(help)
+	default:
+		return fmt.Errorf("unknown command %q, try help", args[0])
+	}
+	return nil
+}
+
+func intArg(args []string, i int) (int, error) {
+	if i >= len(args) {
+		return 0, fmt.Errorf("%s: missing argument", args[0])
+	}
+	return strconv.Atoi(args[i])
+}
+
+func opArg(args []string, i int) (int, error) {
+	if i >= len(args) {
+		return 0, fmt.Errorf("%s: missing argument", args[0])
+	}
+	if op, ok := intcode.Opcode(args[i]); ok {
+		return op, nil
+	}
+	return strconv.Atoi(args[i])
+}
+
+func sortedKeys(m interface{}) []int {
+	var rv []int
+	switch m := m.(type) {
+	case map[int]bool:
+		for k := range m {
+			rv = append(rv, k)
+		}
+	case map[int]int:
+		for k := range m {
+			rv = append(rv, k)
+		}
+	}
+	sort.Ints(rv)
+	return rv
+}
+
+func main() {
+	path := "input"
+	if len(os.Args) > 1 {
+		path = os.Args[1]
+	}
+
+	mem, err := intcode.Load(path)
+	if err != nil {
+		panic(err)
+	}
+
+	d := mkDebugger(mem)
+	d.list(0, 0, listAfter)
+
+	s := bufio.NewScanner(os.Stdin)
+	var last []string
+	for {
+		fmt.Print("(icdb) ")
+		if !s.Scan() {
+			fmt.Println()
+			return
+		}
+		args := strings.Fields(s.Text())
+		if len(args) == 0 {
+			// repeat the previous command, like gdb
+			args = last
+		}
+		if len(args) == 0 {
+			continue
+		}
+		if args[0] == "q" || args[0] == "quit" {
+			return
+		}
+		if err := d.exec(args); err != nil {
+			fmt.Println(err)
+		}
+		last = args
+	}
+}
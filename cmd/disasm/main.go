@@ -0,0 +1,23 @@
+package main
+
+import (
+	"os"
+
+	"github.com/eaceaser/advent-2019/intcode"
+)
+
+func main() {
+	path := "input"
+	if len(os.Args) > 1 {
+		path = os.Args[1]
+	}
+
+	mem, err := intcode.Load(path)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := intcode.Disassemble(mem).Print(os.Stdout); err != nil {
+		panic(err)
+	}
+}
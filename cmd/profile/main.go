@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/eaceaser/advent-2019/intcode"
+)
+
+func parseList(s string) ([]int, error) {
+	var rv []int
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, err
+		}
+		rv = append(rv, v)
+	}
+	return rv, nil
+}
+
+func main() {
+	inputs := flag.String("in", "", "comma separated input values; the last one repeats once they run out")
+	pokes := flag.String("poke", "", "comma separated `addr=value` patches applied before running")
+	top := flag.Int("top", 20, "number of hot spots to report")
+	folded := flag.String("folded", "", "write folded call stacks to `file`")
+	flag.Parse()
+
+	path := "input"
+	if flag.NArg() > 0 {
+		path = flag.Arg(0)
+	}
+
+	mem, err := intcode.Load(path)
+	if err != nil {
+		panic(err)
+	}
+	in, err := parseList(*inputs)
+	if err != nil {
+		panic(err)
+	}
+
+	c := intcode.New("profile", mem)
+	for _, p := range strings.Split(*pokes, ",") {
+		if p == "" {
+			continue
+		}
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 {
+			panic(fmt.Sprintf("bad patch %q", p))
+		}
+		addr, err := strconv.Atoi(kv[0])
+		if err != nil {
+			panic(err)
+		}
+		v, err := strconv.Atoi(kv[1])
+		if err != nil {
+			panic(err)
+		}
+		if err := c.Poke(addr, v); err != nil {
+			panic(err)
+		}
+	}
+
+	prof := intcode.NewProfiler()
+	c.SetProfiler(prof)
+
+	for {
+		s, err := c.Run()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			break
+		}
+		if s == intcode.StateHalted {
+			break
+		}
+		if s == intcode.StateInput {
+			if len(in) == 0 {
+				fmt.Fprintln(os.Stderr, "program requested input but none was given")
+				break
+			}
+			c.In = in[0]
+			if len(in) > 1 {
+				in = in[1:]
+			}
+		}
+	}
+
+	if err := prof.Report(os.Stdout, c, *top); err != nil {
+		panic(err)
+	}
+
+	if *folded != "" {
+		file, err := os.Create(*folded)
+		if err != nil {
+			panic(err)
+		}
+		defer file.Close()
+		if err := prof.WriteFolded(file); err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/eaceaser/advent-2019/intcode"
+)
+
+const (
+	// sliceSteps is how many instructions the machine executes between
+	// looking at commands.
+	sliceSteps = 1 << 12
+	// clientBuffer is how many lines may be waiting for a client before it
+	// is disconnected for falling behind. A slice can output at most one
+	// line per instruction, so this holds several slices of output.
+	clientBuffer = 8 * sliceSteps
+)
+
+const help = `commands, each answered by a line starting with ok or err:
+  in <v> [v...]      queue input values
+  pause              stop executing after the current instruction slice
+  resume             continue executing
+  status             print state, ip, rel, steps, queued input and pause
+  snapshot [name]    save a snapshot as name in the -snapdir directory, or
+                     print it on the ok line
+  reset              restart the program and drop queued input
+  help               print this help
+  quit               close the connection
+events, sent to every client; a client connecting while the program is stopped
+is sent the input, halt or fault event for that stop:
+  out <v>            the program output v
+  input              the program is waiting for input and none is queued
+  halt               the program halted
+  fault <msg>        the program failed; reset to start over`
+
+type server struct {
+	mu   sync.Mutex
+	wake *sync.Cond
+	// out is taken before mu is released by whoever delivers lines, so
+	// clients receive lines in the order their senders held mu.
+	out     sync.Mutex
+	snapdir string
+	c       *intcode.Computer
+	initial *intcode.Computer
+	queue   []int
+	paused  bool
+	fault   error
+	clients map[*client]bool
+}
+
+// client is a connection. Its writer goroutine owns the connection: it sends
+// lines until quit or kick is closed, then closes the connection and done.
+type client struct {
+	conn  net.Conn
+	lines chan string
+	quit  chan struct{}
+	kick  chan struct{}
+	once  sync.Once
+	done  chan struct{}
+}
+
+// send queues l for the client without waiting. A client whose buffer is full
+// has fallen behind and is disconnected.
+func (cl *client) send(l string) {
+	select {
+	case cl.lines <- l:
+	default:
+		cl.once.Do(func() {
+			fmt.Fprintf(os.Stderr, "disconnecting %s: too far behind\n", cl.conn.RemoteAddr())
+			close(cl.kick)
+		})
+	}
+}
+
+func (cl *client) write() {
+	defer close(cl.done)
+	defer cl.conn.Close()
+	w := bufio.NewWriter(cl.conn)
+	for {
+		select {
+		case l := <-cl.lines:
+			fmt.Fprintln(w, l)
+			if len(cl.lines) == 0 && w.Flush() != nil {
+				return
+			}
+		case <-cl.quit:
+			for len(cl.lines) > 0 {
+				fmt.Fprintln(w, <-cl.lines)
+			}
+			w.Flush()
+			return
+		case <-cl.kick:
+			return
+		}
+	}
+}
+
+func mkServer(c *intcode.Computer, snapdir string) *server {
+	s := &server{
+		snapdir: snapdir,
+		c:       c,
+		initial: c.Copy(),
+		clients: map[*client]bool{},
+	}
+	s.wake = sync.NewCond(&s.mu)
+	return s
+}
+
+// runnable reports whether the machine can make progress. s.mu must be held.
+func (s *server) runnable() bool {
+	switch {
+	case s.paused, s.fault != nil, s.c.State() == intcode.StateHalted:
+		return false
+	case s.c.State() == intcode.StateInput:
+		return len(s.queue) > 0
+	}
+	return true
+}
+
+// run executes the machine in slices for as long as the server lives,
+// broadcasting the events of each slice once it is done.
+func (s *server) run() {
+	for {
+		s.mu.Lock()
+		for !s.runnable() {
+			s.wake.Wait()
+		}
+		events := s.slice()
+		to := make([]*client, 0, len(s.clients))
+		for cl := range s.clients {
+			to = append(to, cl)
+		}
+		s.deliver(to, events)
+	}
+}
+
+// deliver sends lines to each of the clients. It must be called with s.mu
+// held, which it releases before sending, so that lines reach every client in
+// the order of the events and commands that produced them.
+func (s *server) deliver(to []*client, lines []string) {
+	s.out.Lock()
+	s.mu.Unlock()
+	defer s.out.Unlock()
+	for _, cl := range to {
+		for _, l := range lines {
+			cl.send(l)
+		}
+	}
+}
+
+// waiting returns the event describing why the machine is stopped, if it is
+// waiting for input, halted or faulted. s.mu must be held.
+func (s *server) waiting() (string, bool) {
+	switch {
+	case s.fault != nil:
+		return "fault " + s.fault.Error(), true
+	case s.c.State() == intcode.StateHalted:
+		return "halt", true
+	case s.c.State() == intcode.StateInput && len(s.queue) == 0:
+		return "input", true
+	}
+	return "", false
+}
+
+// slice executes up to sliceSteps instructions and returns the events they
+// produced. s.mu must be held.
+func (s *server) slice() []string {
+	var events []string
+	for i := 0; i < sliceSteps; i++ {
+		if s.c.State() == intcode.StateInput {
+			s.c.In, s.queue = s.queue[0], s.queue[1:]
+		}
+		st, err := s.c.Step()
+		if err != nil {
+			s.fault = err
+			return append(events, "fault "+err.Error())
+		}
+		switch st {
+		case intcode.StateOutput:
+			events = append(events, "out "+strconv.Itoa(s.c.Out))
+		case intcode.StateHalted:
+			return append(events, "halt")
+		case intcode.StateInput:
+			if len(s.queue) == 0 {
+				return append(events, "input")
+			}
+		}
+	}
+	return events
+}
+
+// exec runs a command for cl and sends its reply. It reports whether the
+// connection should be closed.
+func (s *server) exec(cl *client, args []string) bool {
+	s.mu.Lock()
+	reply, quit := s.command(args)
+	s.deliver([]*client{cl}, []string{reply})
+	return quit
+}
+
+// command runs a command and returns its reply, and whether the connection
+// should be closed. s.mu must be held.
+func (s *server) command(args []string) (string, bool) {
+	switch args[0] {
+	case "in", "input":
+		if len(args) < 2 {
+			return "err usage: in <v> [v...]", false
+		}
+		vals := make([]int, len(args)-1)
+		for i, a := range args[1:] {
+			v, err := strconv.Atoi(a)
+			if err != nil {
+				return "err " + err.Error(), false
+			}
+			vals[i] = v
+		}
+		s.queue = append(s.queue, vals...)
+		s.wake.Broadcast()
+	case "pause":
+		s.paused = true
+	case "resume":
+		s.paused = false
+		s.wake.Broadcast()
+	case "status":
+		status := fmt.Sprintf("ok state=%s ip=%d rel=%d steps=%d queued=%d paused=%t",
+			s.c.State(), s.c.IP(), s.c.Rel(), s.c.Steps(), len(s.queue), s.paused)
+		if s.fault != nil {
+			status += " fault=" + strconv.Quote(s.fault.Error())
+		}
+		return status, false
+	case "snapshot":
+		if len(args) > 1 {
+			name := args[1]
+			switch {
+			case s.snapdir == "":
+				return "err saving snapshots is disabled; start the server with -snapdir", false
+			case name != filepath.Base(name) || name == "." || name == "..":
+				return "err snapshot name must be a plain file name", false
+			}
+			if err := intcode.SaveSnapshot(filepath.Join(s.snapdir, name), s.c); err != nil {
+				return "err " + err.Error(), false
+			}
+			return "ok " + name, false
+		}
+		var buf bytes.Buffer
+		if err := s.c.Snapshot().Write(&buf); err != nil {
+			return "err " + err.Error(), false
+		}
+		return "ok " + strings.TrimSpace(buf.String()), false
+	case "reset":
+		s.c = s.initial.Copy()
+		s.queue = nil
+		s.fault = nil
+		s.wake.Broadcast()
+	case "help":
+		return "ok\n" + help, false
+	case "quit":
+		return "ok", true
+	default:
+		return fmt.Sprintf("err unknown command %q, try help", args[0]), false
+	}
+	return "ok", false
+}
+
+func (s *server) serve(conn net.Conn) {
+	cl := &client{
+		conn:  conn,
+		lines: make(chan string, clientBuffer),
+		quit:  make(chan struct{}),
+		kick:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go cl.write()
+	s.mu.Lock()
+	s.clients[cl] = true
+	// the event for the current stop went out before the client connected
+	var greeting []string
+	if e, ok := s.waiting(); ok {
+		greeting = append(greeting, e)
+	}
+	s.deliver([]*client{cl}, greeting)
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		args := strings.Fields(scanner.Text())
+		if len(args) == 0 {
+			continue
+		}
+		if s.exec(cl, args) {
+			break
+		}
+	}
+
+	s.mu.Lock()
+	delete(s.clients, cl)
+	s.mu.Unlock()
+	close(cl.quit)
+}
+
+// parsePokes parses comma separated addr=value pairs.
+func parsePokes(s string) (map[int]int, error) {
+	rv := map[int]int{}
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("bad poke %q", p)
+		}
+		addr, err := strconv.Atoi(kv[0])
+		if err != nil {
+			return nil, err
+		}
+		v, err := strconv.Atoi(kv[1])
+		if err != nil {
+			return nil, err
+		}
+		rv[addr] = v
+	}
+	return rv, nil
+}
+
+func main() {
+	tcp := flag.String("tcp", "localhost:7019", "listen on TCP `addr`")
+	unix := flag.String("unix", "", "listen on a Unix socket at `path` instead of TCP")
+	snapshot := flag.String("snapshot", "", "start from a snapshot `file` instead of a program image")
+	snapdir := flag.String("snapdir", "", "let clients save snapshots by name into `dir`")
+	pokes := flag.String("poke", "", "comma separated `addr=value` changes to the program, such as 0=2 for the arcade")
+	flag.Parse()
+
+	var c *intcode.Computer
+	if *snapshot != "" {
+		var err error
+		if c, err = intcode.LoadSnapshot(*snapshot); err != nil {
+			panic(err)
+		}
+	} else {
+		path := "input"
+		if flag.NArg() > 0 {
+			path = flag.Arg(0)
+		}
+		mem, err := intcode.Load(path)
+		if err != nil {
+			panic(err)
+		}
+		c = intcode.New("serve", mem)
+	}
+	changes, err := parsePokes(*pokes)
+	if err != nil {
+		panic(err)
+	}
+	for addr, v := range changes {
+		if err := c.Poke(addr, v); err != nil {
+			panic(err)
+		}
+	}
+
+	network, addr := "tcp", *tcp
+	if *unix != "" {
+		network, addr = "unix", *unix
+		// remove a socket left behind by an earlier run
+		if fi, err := os.Stat(addr); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			os.Remove(addr)
+		}
+	}
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Fprintf(os.Stderr, "serving on %s %s\n", network, l.Addr())
+
+	s := mkServer(c, *snapdir)
+	go s.run()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			panic(err)
+		}
+		go s.serve(conn)
+	}
+}
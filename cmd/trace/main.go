@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/eaceaser/advent-2019/intcode"
+)
+
+func parseRange(s string) (int, int, error) {
+	if s == "" {
+		return 0, 0, nil
+	}
+	parts := strings.SplitN(s, "-", 2)
+	from, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(parts) == 1 {
+		return from, from + 1, nil
+	}
+	if parts[1] == "" {
+		return from, 0, nil
+	}
+	to, err := strconv.Atoi(parts[1])
+	return from, to, err
+}
+
+func parseList(s string, f func(string) (int, error)) ([]int, error) {
+	var rv []int
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		v, err := f(p)
+		if err != nil {
+			return nil, err
+		}
+		rv = append(rv, v)
+	}
+	return rv, nil
+}
+
+func opcode(s string) (int, error) {
+	if op, ok := intcode.Opcode(s); ok {
+		return op, nil
+	}
+	return strconv.Atoi(s)
+}
+
+func main() {
+	addrs := flag.String("addr", "", "trace only instructions at addresses `from-to` (to exclusive, optional)")
+	ops := flag.String("op", "", "trace only these comma separated opcodes or mnemonics")
+	steps := flag.String("steps", "", "trace only steps `from-to` (to exclusive, optional)")
+	inputs := flag.String("in", "", "comma separated input values")
+	flag.Parse()
+
+	path := "input"
+	if flag.NArg() > 0 {
+		path = flag.Arg(0)
+	}
+
+	mem, err := intcode.Load(path)
+	if err != nil {
+		panic(err)
+	}
+
+	var filter intcode.TraceFilter
+	if filter.AddrFrom, filter.AddrTo, err = parseRange(*addrs); err != nil {
+		panic(err)
+	}
+	if filter.StepFrom, filter.StepTo, err = parseRange(*steps); err != nil {
+		panic(err)
+	}
+	if filter.Opcodes, err = parseList(*ops, opcode); err != nil {
+		panic(err)
+	}
+	in, err := parseList(*inputs, strconv.Atoi)
+	if err != nil {
+		panic(err)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	t := intcode.NewTracer(w)
+	t.Filter = filter
+
+	c := intcode.New("trace", mem)
+	c.SetTracer(t)
+	for {
+		s, err := c.Run()
+		if err != nil {
+			w.Flush()
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if s == intcode.StateHalted {
+			break
+		}
+		if s == intcode.StateInput {
+			if len(in) == 0 {
+				w.Flush()
+				fmt.Fprintln(os.Stderr, "program requested more input than given")
+				os.Exit(1)
+			}
+			c.In, in = in[0], in[1:]
+		}
+	}
+	if err := t.Err(); err != nil {
+		panic(err)
+	}
+}
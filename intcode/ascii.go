@@ -0,0 +1,186 @@
+package intcode
+
+import (
+	"io"
+	"strconv"
+)
+
+const (
+	asciiMax     = 128
+	asciiNewline = '\n'
+)
+
+// ASCIIOutput is a line of text or a raw value produced by an ASCII program.
+type ASCIIOutput struct {
+	// Line is the text of the line, without its newline.
+	Line string
+	// Value holds an output value that is not text, when Raw is set.
+	Value int
+	Raw   bool
+}
+
+func (o ASCIIOutput) String() string {
+	if o.Raw {
+		return strconv.Itoa(o.Value)
+	}
+	return o.Line
+}
+
+// ASCIIDecoder turns a stream of output values into lines of text. Values
+// outside the ASCII range are passed through as raw values.
+type ASCIIDecoder struct {
+	buf []byte
+}
+
+// Decode consumes one output value, calling emit for each line or raw value
+// it completes. A raw value first flushes any partial line so output stays in
+// order.
+func (d *ASCIIDecoder) Decode(v int, emit func(ASCIIOutput)) {
+	switch {
+	case v == asciiNewline:
+		emit(ASCIIOutput{Line: string(d.buf)})
+		d.buf = d.buf[:0]
+	case v >= 0 && v < asciiMax:
+		d.buf = append(d.buf, byte(v))
+	default:
+		d.Flush(emit)
+		emit(ASCIIOutput{Value: v, Raw: true})
+	}
+}
+
+// Flush emits any partial line, such as a prompt printed without a newline.
+func (d *ASCIIDecoder) Flush(emit func(ASCIIOutput)) {
+	if len(d.buf) > 0 {
+		emit(ASCIIOutput{Line: string(d.buf)})
+		d.buf = d.buf[:0]
+	}
+}
+
+// EncodeASCII returns the input values for line: its character codes followed
+// by a newline.
+func EncodeASCII(line string) []int {
+	rv := make([]int, 0, len(line)+1)
+	for i := 0; i < len(line); i++ {
+		rv = append(rv, int(line[i]))
+	}
+	return append(rv, asciiNewline)
+}
+
+// DecodeASCIIChan decodes the values read from in until it is closed, sending
+// the result to out, which is then closed. It adapts the output channel of
+// RunChan.
+func DecodeASCIIChan(in <-chan int, out chan<- ASCIIOutput) {
+	var d ASCIIDecoder
+	emit := func(o ASCIIOutput) { out <- o }
+	for v := range in {
+		d.Decode(v, emit)
+	}
+	d.Flush(emit)
+	close(out)
+}
+
+// EncodeASCIIChan sends each line read from in to out as character codes
+// followed by a newline, until in is closed. It adapts the input channel of
+// RunChan. out is not closed.
+func EncodeASCIIChan(in <-chan string, out chan<- int) {
+	for line := range in {
+		for _, v := range EncodeASCII(line) {
+			out <- v
+		}
+	}
+}
+
+// ASCII drives a Computer running a program that talks in lines of text.
+//
+// It is used like the computer itself: Run returns StateOutput with Out set to
+// the next line or raw value, StateInput once the program needs input that
+// has not been queued with WriteLine, and StateHalted when the program is
+// done. A partial line is returned as output before input is requested or the
+// program halts, so prompts are not lost.
+type ASCII struct {
+	Out ASCIIOutput
+
+	c       *Computer
+	dec     ASCIIDecoder
+	queue   []ASCIIOutput
+	pending []int
+}
+
+// NewASCII returns an ASCII adapter for c.
+func NewASCII(c *Computer) *ASCII {
+	return &ASCII{c: c}
+}
+
+// Computer returns the underlying computer.
+func (a *ASCII) Computer() *Computer {
+	return a.c
+}
+
+// WriteLine queues line as input for the program.
+func (a *ASCII) WriteLine(line string) {
+	a.pending = append(a.pending, EncodeASCII(line)...)
+}
+
+// Run executes until the program produces a line or raw value, needs a line
+// of input, or halts.
+func (a *ASCII) Run() (State, error) {
+	for {
+		if len(a.queue) > 0 {
+			a.Out = a.queue[0]
+			a.queue = a.queue[1:]
+			return StateOutput, nil
+		}
+
+		switch s := a.c.State(); s {
+		case StateInput, StateHalted:
+			if s == StateInput && len(a.pending) > 0 {
+				a.c.In = a.pending[0]
+				a.pending = a.pending[1:]
+				break
+			}
+			a.dec.Flush(a.emit)
+			if len(a.queue) > 0 {
+				continue
+			}
+			return s, nil
+		}
+
+		s, err := a.c.Run()
+		if err != nil {
+			return s, err
+		}
+		if s == StateOutput {
+			a.dec.Decode(a.c.Out, a.emit)
+		}
+	}
+}
+
+// RunChan runs the program to completion, reading lines from input and
+// sending its output to output. The output channel is not closed. If input is
+// closed while the program is waiting for it, RunChan returns
+// io.ErrUnexpectedEOF.
+func (a *ASCII) RunChan(input <-chan string, output chan<- ASCIIOutput) error {
+	for {
+		s, err := a.Run()
+		if err != nil {
+			return err
+		}
+
+		switch s {
+		case StateInput:
+			line, ok := <-input
+			if !ok {
+				return io.ErrUnexpectedEOF
+			}
+			a.WriteLine(line)
+		case StateOutput:
+			output <- a.Out
+		case StateHalted:
+			return nil
+		}
+	}
+}
+
+func (a *ASCII) emit(o ASCIIOutput) {
+	a.queue = append(a.queue, o)
+}
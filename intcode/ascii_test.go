@@ -0,0 +1,148 @@
+package intcode
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func asciiLine(s string) ASCIIOutput { return ASCIIOutput{Line: s} }
+func asciiRaw(v int) ASCIIOutput     { return ASCIIOutput{Value: v, Raw: true} }
+
+func TestASCIIDecoder(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []int
+		want []ASCIIOutput
+	}{
+		{"lines", []int{'h', 'i', '\n', '\n', 'y', 'o', '\n'}, []ASCIIOutput{asciiLine("hi"), asciiLine(""), asciiLine("yo")}},
+		{"partial", []int{'>', ' '}, []ASCIIOutput{asciiLine("> ")}},
+		{"raw-flushes", []int{'a', 128, 'b', '\n'}, []ASCIIOutput{asciiLine("a"), asciiRaw(128), asciiLine("b")}},
+		{"raw-range", []int{127, -1, 1 << 40}, []ASCIIOutput{asciiLine("\x7f"), asciiRaw(-1), asciiRaw(1 << 40)}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var d ASCIIDecoder
+			var got []ASCIIOutput
+			emit := func(o ASCIIOutput) { got = append(got, o) }
+			for _, v := range tc.in {
+				d.Decode(v, emit)
+			}
+			d.Flush(emit)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEncodeASCII(t *testing.T) {
+	if got, want := EncodeASCII("NOT J"), []int{'N', 'O', 'T', ' ', 'J', '\n'}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := EncodeASCII(""), []int{'\n'}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestASCIIChan(t *testing.T) {
+	lines := make(chan string, 2)
+	lines <- "ab"
+	lines <- "c"
+	close(lines)
+	codes := make(chan int, 16)
+	EncodeASCIIChan(lines, codes)
+	close(codes)
+
+	out := make(chan ASCIIOutput, 16)
+	DecodeASCIIChan(codes, out)
+	var got []ASCIIOutput
+	for o := range out {
+		got = append(got, o)
+	}
+	if want := []ASCIIOutput{asciiLine("ab"), asciiLine("c")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// echoProgram prompts with "? ", echoes a line of input, then outputs a
+// value that is not text and halts.
+const echoProgram = `
+	out #63
+	out #32
+loop:
+	in [ch]
+	eq [ch], #10, [nl]
+	jt [nl], #done
+	out [ch]
+	jt #1, #loop
+done:
+	out #10
+	out #1000
+	hlt
+ch: .data 0
+nl: .data 0`
+
+func newEcho(t *testing.T) *ASCII {
+	t.Helper()
+	mem, err := Assemble(echoProgram)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewASCII(New("echo", mem))
+}
+
+func TestASCIIRun(t *testing.T) {
+	a := newEcho(t)
+	steps := []struct {
+		write string
+		state State
+		out   ASCIIOutput
+	}{
+		{state: StateOutput, out: asciiLine("? ")},
+		{state: StateInput},
+		{write: "abc", state: StateOutput, out: asciiLine("abc")},
+		{state: StateOutput, out: asciiRaw(1000)},
+		{state: StateHalted},
+	}
+	for i, st := range steps {
+		if st.write != "" {
+			a.WriteLine(st.write)
+		}
+		s, err := a.Run()
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if s != st.state {
+			t.Fatalf("step %d: state %s, want %s", i, s, st.state)
+		}
+		if s == StateOutput && a.Out != st.out {
+			t.Errorf("step %d: output %q, want %q", i, a.Out, st.out)
+		}
+	}
+}
+
+func TestASCIIRunChan(t *testing.T) {
+	input := make(chan string, 1)
+	input <- "hello"
+	output := make(chan ASCIIOutput, 8)
+	if err := newEcho(t).RunChan(input, output); err != nil {
+		t.Fatal(err)
+	}
+	close(output)
+	var got []ASCIIOutput
+	for o := range output {
+		got = append(got, o)
+	}
+	if want := []ASCIIOutput{asciiLine("? "), asciiLine("hello"), asciiRaw(1000)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	closed := make(chan string)
+	close(closed)
+	err := newEcho(t).RunChan(closed, make(chan ASCIIOutput, 8))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("error = %v with input closed, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
@@ -0,0 +1,327 @@
+package intcode
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// writeParams lists, per opcode, the parameter the instruction writes to.
+var writeParams = map[int]int{
+	OpcodeAdd:      2,
+	OpcodeMultiply: 2,
+	OpcodeInput:    0,
+	OpcodeLT:       2,
+	OpcodeEql:      2,
+}
+
+// Assemble translates intcode assembly into a program image.
+//
+// Each line holds an optional label ("name:"), then an instruction, a directive
+// or a macro invocation. Comments start with ';'. Instructions use the
+// mnemonics printed by the disassembler and their operands are written as
+// #value (immediate), [value] (position) or rb+value (relative), where value
+// is an integer, a label, or a label plus or minus an integer.
+//
+// Directives:
+//
+//	.data v, v, ...     emit raw words
+//	.macro name a, b    begin a macro taking parameters a and b
+//	.endm               end a macro
+//
+// Inside a macro body \a expands to the argument passed for a, and \@ expands
+// to a number unique to each invocation, for making local labels.
+func Assemble(src string) ([]int, error) {
+	return assemble(src, nil)
+}
+
+// Assemble is like the package's Assemble, but also accepts the mnemonics of
+// the registry's extension opcodes.
+func (r *Registry) Assemble(src string) ([]int, error) {
+	return assemble(src, r)
+}
+
+func assemble(src string, r *Registry) ([]int, error) {
+	lines, err := expandMacros(strings.Split(src, "\n"), r)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := map[string]int{}
+	addr := 0
+	for _, l := range lines {
+		if l.label != "" {
+			if _, dup := labels[l.label]; dup {
+				return nil, l.errorf("duplicate label %q", l.label)
+			}
+			labels[l.label] = addr
+		}
+		switch {
+		case l.op == "":
+		case l.op == ".data":
+			addr += len(l.args)
+		default:
+			op, ok := r.opcode(l.op)
+			if !ok {
+				return nil, l.errorf("unknown instruction %q", l.op)
+			}
+			n, _, _ := r.spec(op)
+			addr += n + 1
+		}
+	}
+
+	mem := make([]int, 0, addr)
+	for _, l := range lines {
+		switch l.op {
+		case "":
+		case ".data":
+			for _, a := range l.args {
+				v, err := evalValue(a, labels)
+				if err != nil {
+					return nil, l.errorf("%v", err)
+				}
+				mem = append(mem, v)
+			}
+		default:
+			words, err := l.encode(labels, r)
+			if err != nil {
+				return nil, err
+			}
+			mem = append(mem, words...)
+		}
+	}
+	return mem, nil
+}
+
+type asmLine struct {
+	num   int
+	label string
+	op    string
+	args  []string
+}
+
+func (l asmLine) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", l.num, fmt.Sprintf(format, args...))
+}
+
+func (l asmLine) encode(labels map[string]int, r *Registry) ([]int, error) {
+	op, _ := r.opcode(l.op)
+	n, _, _ := r.spec(op)
+	if len(l.args) != n {
+		return nil, l.errorf("%s takes %d operands, got %d", l.op, n, len(l.args))
+	}
+
+	rv := make([]int, n+1)
+	code := op
+	for i, a := range l.args {
+		mode, v, err := parseOperand(a, labels)
+		if err != nil {
+			return nil, l.errorf("%v", err)
+		}
+		if r.writes(op, i) && mode == ModeImmediate {
+			return nil, l.errorf("operand %d of %s cannot be immediate", i+1, l.op)
+		}
+		code += mode * pow10(i+2)
+		rv[i+1] = v
+	}
+	rv[0] = code
+	return rv, nil
+}
+
+func parseOperand(s string, labels map[string]int) (int, int, error) {
+	switch {
+	case strings.HasPrefix(s, "#"):
+		v, err := evalValue(s[1:], labels)
+		return ModeImmediate, v, err
+	case strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]"):
+		v, err := evalValue(s[1:len(s)-1], labels)
+		return ModePosition, v, err
+	case s == "rb":
+		return ModeRelative, 0, nil
+	case strings.HasPrefix(s, "rb+") || strings.HasPrefix(s, "rb-"):
+		v, err := evalValue(s[2:], labels)
+		return ModeRelative, v, err
+	}
+	return 0, 0, fmt.Errorf("bad operand %q", s)
+}
+
+// evalValue evaluates a sum of integers and labels such as "end-1".
+func evalValue(s string, labels map[string]int) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("missing value")
+	}
+
+	total := 0
+	sign := 1
+	start := 0
+	if s[0] == '+' || s[0] == '-' {
+		if s[0] == '-' {
+			sign = -1
+		}
+		start = 1
+	}
+	for i := start; i <= len(s); i++ {
+		if i < len(s) && s[i] != '+' && s[i] != '-' {
+			continue
+		}
+		term := strings.TrimSpace(s[start:i])
+		v, err := evalTerm(term, labels)
+		if err != nil {
+			return 0, err
+		}
+		total += sign * v
+		if i < len(s) {
+			sign = 1
+			if s[i] == '-' {
+				sign = -1
+			}
+		}
+		start = i + 1
+	}
+	return total, nil
+}
+
+func evalTerm(s string, labels map[string]int) (int, error) {
+	if s == "" {
+		return 0, fmt.Errorf("missing value")
+	}
+	if unicode.IsDigit(rune(s[0])) {
+		return strconv.Atoi(s)
+	}
+	v, ok := labels[s]
+	if !ok {
+		return 0, fmt.Errorf("undefined label %q", s)
+	}
+	return v, nil
+}
+
+type asmMacro struct {
+	params []string
+	body   []asmLine
+}
+
+// expandMacros parses source lines, collecting macro definitions and
+// replacing their invocations with the substituted bodies.
+func expandMacros(src []string, r *Registry) ([]asmLine, error) {
+	macros := map[string]*asmMacro{}
+	var cur *asmMacro
+	var rv []asmLine
+	invocations := 0
+
+	var expand func(l asmLine, depth int) error
+	expand = func(l asmLine, depth int) error {
+		m, ok := macros[l.op]
+		if !ok {
+			rv = append(rv, l)
+			return nil
+		}
+		if depth > 100 {
+			return l.errorf("macro %s expands too deeply", l.op)
+		}
+		if len(l.args) != len(m.params) {
+			return l.errorf("macro %s takes %d arguments, got %d", l.op, len(m.params), len(l.args))
+		}
+		invocations++
+		if l.label != "" {
+			rv = append(rv, asmLine{num: l.num, label: l.label})
+		}
+		repl := make([]string, 0, 2*len(m.params)+2)
+		repl = append(repl, `\@`, strconv.Itoa(invocations))
+		// longest names first so \ab is not taken for \a followed by b
+		order := make([]int, len(m.params))
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(i, j int) bool {
+			return len(m.params[order[i]]) > len(m.params[order[j]])
+		})
+		for _, i := range order {
+			repl = append(repl, `\`+m.params[i], l.args[i])
+		}
+		r := strings.NewReplacer(repl...)
+		for _, b := range m.body {
+			sub := asmLine{num: l.num, label: r.Replace(b.label), op: b.op}
+			for _, a := range b.args {
+				sub.args = append(sub.args, r.Replace(a))
+			}
+			if err := expand(sub, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for i, text := range src {
+		l, err := parseLine(i+1, text)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case l.op == ".macro":
+			if cur != nil {
+				return nil, l.errorf("nested .macro")
+			}
+			if len(l.args) == 0 {
+				return nil, l.errorf(".macro needs a name")
+			}
+			fields := strings.Fields(l.args[0])
+			name := strings.ToLower(fields[0])
+			params := append(fields[1:], l.args[1:]...)
+			if _, ok := r.opcode(name); ok {
+				return nil, l.errorf("macro %s shadows an instruction", name)
+			}
+			cur = &asmMacro{params: params}
+			macros[name] = cur
+		case l.op == ".endm":
+			if cur == nil {
+				return nil, l.errorf(".endm without .macro")
+			}
+			cur = nil
+		case cur != nil:
+			cur.body = append(cur.body, l)
+		default:
+			if err := expand(l, 0); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if cur != nil {
+		return nil, fmt.Errorf("unterminated .macro")
+	}
+	return rv, nil
+}
+
+func parseLine(num int, text string) (asmLine, error) {
+	l := asmLine{num: num}
+	if i := strings.IndexByte(text, ';'); i >= 0 {
+		text = text[:i]
+	}
+	text = strings.TrimSpace(text)
+
+	if i := strings.IndexByte(text, ':'); i >= 0 {
+		l.label = strings.TrimSpace(text[:i])
+		if l.label == "" || strings.ContainsAny(l.label, " \t") {
+			return l, l.errorf("bad label %q", l.label)
+		}
+		text = strings.TrimSpace(text[i+1:])
+	}
+	if text == "" {
+		return l, nil
+	}
+
+	rest := ""
+	if i := strings.IndexFunc(text, unicode.IsSpace); i >= 0 {
+		text, rest = text[:i], strings.TrimSpace(text[i:])
+	}
+	l.op = strings.ToLower(text)
+	if rest != "" {
+		for _, a := range strings.Split(rest, ",") {
+			l.args = append(l.args, strings.TrimSpace(a))
+		}
+	}
+	return l, nil
+}
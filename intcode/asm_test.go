@@ -0,0 +1,133 @@
+package intcode
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var asmTests = []struct {
+	name string
+	src  string
+	want []int
+}{
+	{name: "modes", src: `
+		add #1, [5], rb-2
+		hlt`,
+		want: []int{20101, 1, 5, -2, 99}},
+	{name: "labels", src: `
+		start: in [x]       ; read
+		       out [x]
+		       jt #1, #start
+		x:     .data 0`,
+		want: []int{3, 7, 4, 7, 1105, 1, 0, 0}},
+	{name: "label-offset", src: `
+		out [buf+1]
+		hlt
+		buf: .data 5, 6`,
+		want: []int{4, 4, 99, 5, 6}},
+	{name: "macros", src: `
+		.macro emit v
+		  out #\v
+		.endm
+		.macro skip
+		  jt #1, #next\@
+		  .data 42
+		next\@:
+		.endm
+		emit 7
+		skip
+		skip
+		hlt`,
+		want: []int{104, 7, 1105, 1, 6, 42, 1105, 1, 10, 42, 99}},
+}
+
+func TestAssemble(t *testing.T) {
+	for _, tc := range asmTests {
+		t.Run(tc.name, func(t *testing.T) {
+			mem, err := Assemble(tc.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(mem, tc.want) {
+				t.Errorf("got %v, want %v", mem, tc.want)
+			}
+		})
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"immediate-write", "add #1, #2, #3", "cannot be immediate"},
+		{"unknown", "foo [1]", "unknown instruction"},
+		{"operands", "add #1, #2", "takes 3 operands"},
+		{"duplicate-label", "x: hlt\nx: hlt", "duplicate label"},
+		{"unterminated-macro", ".macro m\nhlt", "unterminated"},
+		{"undefined-label", "out [nowhere]", "nowhere"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Assemble(tc.src)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("error = %v, want one containing %q", err, tc.want)
+			}
+		})
+	}
+}
+
+// reassemble turns a listing back into assembly source.
+func reassemble(l *Listing) string {
+	var b strings.Builder
+	for addr := 0; addr < len(l.Mem); {
+		if label, ok := l.Labels[addr]; ok {
+			fmt.Fprintf(&b, "%s:\n", label)
+		}
+		if inst, ok := l.Code[addr]; ok {
+			fmt.Fprintln(&b, inst.format(l.Labels))
+			addr += inst.Len()
+			continue
+		}
+		fmt.Fprintf(&b, ".data %d\n", l.Mem[addr])
+		addr++
+	}
+	return b.String()
+}
+
+func TestAssembleRoundTrip(t *testing.T) {
+	images := map[string][]int{}
+	for _, tc := range asmTests {
+		images["asm/"+tc.name] = tc.want
+	}
+	for _, tc := range conformance {
+		mem, err := Parse(tc.prog)
+		if err != nil {
+			t.Fatal(err)
+		}
+		images[tc.name] = mem
+	}
+	for _, day := range []string{"2", "5", "9", "13", "15"} {
+		mem, err := Load("../" + day + "/input")
+		if err != nil {
+			t.Fatal(err)
+		}
+		images["day"+day] = mem
+	}
+
+	for name, mem := range images {
+		t.Run(name, func(t *testing.T) {
+			src := reassemble(Disassemble(mem))
+			got, err := Assemble(src)
+			if err != nil {
+				t.Fatalf("%v in\n%s", err, src)
+			}
+			if !reflect.DeepEqual(got, mem) {
+				t.Errorf("reassembled to %v, want %v from\n%s", got, mem, src)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package intcode
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+const droidMoves = 2000
+
+func loadDay(b *testing.B, day string) []int {
+	mem, err := Load(filepath.Join("..", day, "input"))
+	if err != nil {
+		b.Skip(err)
+	}
+	return mem
+}
+
+// benchRun runs mem to completion once per iteration, answering the n-th
+// input request with input(n) and stopping after limit inputs if limit > 0.
+func benchRun(b *testing.B, mem []int, setup func(*Computer), input func(n int) int, limit int) {
+	b.ReportAllocs()
+	steps := 0
+	for i := 0; i < b.N; i++ {
+		c := New("bench", mem)
+		if setup != nil {
+			setup(c)
+		}
+		n := 0
+	Run:
+		for {
+			s, err := c.Run()
+			if err != nil {
+				b.Fatal(err)
+			}
+			switch s {
+			case StateInput:
+				if limit > 0 && n == limit {
+					break Run
+				}
+				c.In = input(n)
+				n++
+			case StateHalted:
+				break Run
+			}
+		}
+		steps = c.Steps()
+	}
+	b.ReportMetric(float64(steps), "instructions/op")
+}
+
+// BenchmarkDay9 runs the BOOST program in sensor boost mode.
+func BenchmarkDay9(b *testing.B) {
+	mem := loadDay(b, "9")
+	benchRun(b, mem, nil, func(int) int { return 2 }, 0)
+}
+
+// BenchmarkDay13 plays the arcade game with the joystick held still until the
+// ball is lost.
+func BenchmarkDay13(b *testing.B) {
+	mem := loadDay(b, "13")
+	setup := func(c *Computer) { c.Poke(0, 2) }
+	benchRun(b, mem, setup, func(int) int { return 0 }, 0)
+}
+
+// BenchmarkDay15 drives the repair droid through a fixed pseudo-random walk.
+func BenchmarkDay15(b *testing.B) {
+	mem := loadDay(b, "15")
+	input := func(n int) int { return (n*7+n/3)%4 + 1 }
+	benchRun(b, mem, nil, input, droidMoves)
+}
@@ -0,0 +1,353 @@
+package intcode
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+)
+
+// BigComputer is an intcode machine with arbitrary-precision values. It runs
+// the same instruction set as Computer and is driven the same way, but values
+// never overflow. Addresses must still fit in an int.
+type BigComputer struct {
+	Name string
+	In   *big.Int
+	Out  *big.Int
+
+	mem    map[int]*big.Int
+	limit  int
+	tp     int
+	ip     int
+	rel    int
+	opIP   int
+	state  State
+	steps  int
+	budget int
+}
+
+// NewBig returns a BigComputer executing the program image mem.
+func NewBig(name string, mem []*big.Int) *BigComputer {
+	c := &BigComputer{
+		Name: name,
+		In:   new(big.Int),
+		Out:  new(big.Int),
+		mem:  make(map[int]*big.Int, len(mem)),
+	}
+	for i, v := range mem {
+		if v.Sign() != 0 {
+			c.mem[i] = new(big.Int).Set(v)
+		}
+	}
+	return c
+}
+
+// ParseBig parses a comma separated intcode program without limiting the size
+// of its values.
+func ParseBig(s string) ([]*big.Int, error) {
+	words := strings.Split(strings.TrimSpace(s), ",")
+	mem := make([]*big.Int, len(words))
+	for i, w := range words {
+		v, ok := new(big.Int).SetString(strings.TrimSpace(w), 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid word %q", w)
+		}
+		mem[i] = v
+	}
+	return mem, nil
+}
+
+// LoadBig reads and parses the intcode program in the named file with
+// arbitrary-precision values.
+func LoadBig(path string) ([]*big.Int, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseBig(string(b))
+}
+
+// SetMemLimit caps the number of addressable memory cells, as for Computer.
+func (c *BigComputer) SetMemLimit(n int) { c.limit = n }
+
+// SetBudget limits the total number of instructions executed, as for
+// Computer.
+func (c *BigComputer) SetBudget(n int) { c.budget = n }
+
+// State returns the state the computer last stopped in.
+func (c *BigComputer) State() State { return c.state }
+
+// IP returns the instruction pointer.
+func (c *BigComputer) IP() int { return c.ip }
+
+// Rel returns the relative base.
+func (c *BigComputer) Rel() int { return c.rel }
+
+// Steps returns the number of instructions executed so far.
+func (c *BigComputer) Steps() int { return c.steps }
+
+// Peek returns a copy of the value at addr.
+func (c *BigComputer) Peek(addr int) *big.Int {
+	v, err := c.get(addr)
+	if err != nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(v)
+}
+
+// Poke sets the value at addr.
+func (c *BigComputer) Poke(addr int, v *big.Int) error {
+	return c.set(addr, v)
+}
+
+// Run executes until the program requests input, produces output, or halts.
+func (c *BigComputer) Run() (State, error) {
+	switch c.state {
+	case StateRunning, StateOutput:
+	case StateInput:
+		if err := c.set(c.tp, c.In); err != nil {
+			return c.state, c.located(err)
+		}
+	default:
+		return c.state, c.located(&InvalidStateError{State: c.state})
+	}
+	c.state = StateRunning
+
+	for c.state == StateRunning {
+		c.opIP = c.ip
+		if c.budget > 0 && c.steps >= c.budget {
+			return 0, c.located(&BudgetError{Budget: c.budget})
+		}
+		if err := c.step(); err != nil {
+			return 0, c.located(err)
+		}
+		c.steps++
+	}
+	return c.state, nil
+}
+
+// RunChan runs the program to completion, reading input from input and
+// writing output to output. The output channel is not closed.
+func (c *BigComputer) RunChan(input <-chan *big.Int, output chan<- *big.Int) error {
+	for {
+		s, err := c.Run()
+		if err != nil {
+			return err
+		}
+
+		switch s {
+		case StateInput:
+			c.In = <-input
+		case StateOutput:
+			output <- new(big.Int).Set(c.Out)
+		case StateHalted:
+			return nil
+		}
+	}
+}
+
+func (c *BigComputer) step() error {
+	word, err := c.read()
+	if err != nil {
+		return err
+	}
+	if !word.IsInt64() {
+		return &IllegalOpcodeError{Opcode: -1}
+	}
+	opcode, modes := ParseOpcode(int(word.Int64()))
+	n, ok := paramCounts[opcode]
+	if !ok {
+		return &IllegalOpcodeError{Opcode: opcode}
+	}
+	modes = pad(modes, n)
+
+	switch opcode {
+	case OpcodeAdd, OpcodeMultiply, OpcodeLT, OpcodeEql:
+		a, err := c.param(modes[0])
+		if err != nil {
+			return err
+		}
+		b, err := c.param(modes[1])
+		if err != nil {
+			return err
+		}
+		dest, err := c.dest(modes[2])
+		if err != nil {
+			return err
+		}
+		v := new(big.Int)
+		switch opcode {
+		case OpcodeAdd:
+			v.Add(a, b)
+		case OpcodeMultiply:
+			v.Mul(a, b)
+		case OpcodeLT:
+			if a.Cmp(b) < 0 {
+				v.SetInt64(1)
+			}
+		case OpcodeEql:
+			if a.Cmp(b) == 0 {
+				v.SetInt64(1)
+			}
+		}
+		return c.set(dest, v)
+	case OpcodeInput:
+		dest, err := c.dest(modes[0])
+		if err != nil {
+			return err
+		}
+		c.tp = dest
+		c.state = StateInput
+	case OpcodeOutput:
+		v, err := c.param(modes[0])
+		if err != nil {
+			return err
+		}
+		c.Out = new(big.Int).Set(v)
+		c.state = StateOutput
+	case OpcodeJmpIfT, OpcodeJmpIfF:
+		cond, err := c.param(modes[0])
+		if err != nil {
+			return err
+		}
+		target, err := c.param(modes[1])
+		if err != nil {
+			return err
+		}
+		if (cond.Sign() != 0) == (opcode == OpcodeJmpIfT) {
+			if c.ip, err = c.addr(target); err != nil {
+				return err
+			}
+		}
+	case OpcodeRelAdj:
+		v, err := c.param(modes[0])
+		if err != nil {
+			return err
+		}
+		adj, err := c.addr(v)
+		if err != nil {
+			return err
+		}
+		c.rel += adj
+	case OpcodeDie:
+		c.state = StateHalted
+	}
+	return nil
+}
+
+func (c *BigComputer) param(mode int) (*big.Int, error) {
+	p, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	switch mode {
+	case ModeImmediate:
+		return p, nil
+	case ModePosition, ModeRelative:
+		addr, err := c.addr(p)
+		if err != nil {
+			return nil, err
+		}
+		if mode == ModeRelative {
+			addr += c.rel
+		}
+		return c.get(addr)
+	default:
+		return nil, &IllegalModeError{Mode: mode}
+	}
+}
+
+func (c *BigComputer) dest(mode int) (int, error) {
+	p, err := c.read()
+	if err != nil {
+		return 0, err
+	}
+	addr, err := c.addr(p)
+	if err != nil {
+		return 0, err
+	}
+	switch mode {
+	case ModePosition:
+		return addr, nil
+	case ModeImmediate:
+		return 0, &WriteToImmediateError{}
+	case ModeRelative:
+		return addr + c.rel, nil
+	default:
+		return 0, &IllegalModeError{Mode: mode}
+	}
+}
+
+func (c *BigComputer) addr(v *big.Int) (int, error) {
+	if !v.IsInt64() || int64(int(v.Int64())) != v.Int64() {
+		// too large to represent; report it as the largest negative address
+		return 0, &InvalidAddressError{Addr: minInt, Limit: c.limit}
+	}
+	return int(v.Int64()), nil
+}
+
+func (c *BigComputer) read() (*big.Int, error) {
+	v, err := c.get(c.ip)
+	if err != nil {
+		return nil, err
+	}
+	c.ip++
+	return v, nil
+}
+
+// located fills in the fault location of errors raised at the current
+// instruction. Values that do not fit in an int are shown as zero.
+func (c *BigComputer) located(err error) error {
+	f, ok := err.(faulter)
+	if !ok {
+		return err
+	}
+	loc := f.fault()
+	loc.IP = c.opIP
+	loc.Word = toInt(c.Peek(c.opIP))
+	loc.Step = c.steps
+	loc.Excerpt = make([]int, excerptLen)
+	for i := range loc.Excerpt {
+		loc.Excerpt[i] = toInt(c.Peek(c.opIP + i))
+	}
+	return err
+}
+
+func toInt(v *big.Int) int {
+	if !v.IsInt64() || int64(int(v.Int64())) != v.Int64() {
+		return 0
+	}
+	return int(v.Int64())
+}
+
+var bigZero = new(big.Int)
+
+func (c *BigComputer) check(addr int) error {
+	if addr < 0 || (c.limit > 0 && addr >= c.limit) {
+		return &InvalidAddressError{Addr: addr, Limit: c.limit}
+	}
+	return nil
+}
+
+// get returns the stored value itself; callers must not modify it.
+func (c *BigComputer) get(addr int) (*big.Int, error) {
+	if err := c.check(addr); err != nil {
+		return nil, err
+	}
+	if v, ok := c.mem[addr]; ok {
+		return v, nil
+	}
+	return bigZero, nil
+}
+
+func (c *BigComputer) set(addr int, v *big.Int) error {
+	if err := c.check(addr); err != nil {
+		return err
+	}
+	if v.Sign() == 0 {
+		delete(c.mem, addr)
+		return nil
+	}
+	c.mem[addr] = new(big.Int).Set(v)
+	return nil
+}
@@ -0,0 +1,145 @@
+package intcode
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func mustBig(t *testing.T, s string) *big.Int {
+	t.Helper()
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		t.Fatalf("bad number %q", s)
+	}
+	return v
+}
+
+// runBigProgram runs prog to completion on a BigComputer, feeding it in, and
+// returns the computer and its outputs.
+func runBigProgram(t *testing.T, prog string, in []*big.Int) (*BigComputer, []*big.Int, error) {
+	t.Helper()
+	mem, err := ParseBig(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewBig("test", mem)
+	var out []*big.Int
+	for {
+		s, err := c.Run()
+		if err != nil {
+			return c, out, err
+		}
+		switch s {
+		case StateInput:
+			if len(in) == 0 {
+				t.Fatal("program wants more input")
+			}
+			c.In, in = in[0], in[1:]
+		case StateOutput:
+			out = append(out, new(big.Int).Set(c.Out))
+		case StateHalted:
+			return c, out, nil
+		}
+	}
+}
+
+func TestBigConformance(t *testing.T) {
+	for _, tc := range conformance {
+		t.Run(tc.name, func(t *testing.T) {
+			mem, err := ParseBig(tc.prog)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := NewBig("conformance", mem)
+			for addr, v := range tc.pokes {
+				if err := c.Poke(addr, big.NewInt(int64(v))); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			in := tc.in
+			var out []int
+			for done := false; !done; {
+				s, err := c.Run()
+				if err != nil {
+					t.Fatal(err)
+				}
+				switch s {
+				case StateInput:
+					c.In, in = big.NewInt(int64(in[0])), in[1:]
+				case StateOutput:
+					out = append(out, toInt(c.Out))
+				case StateHalted:
+					done = true
+				}
+			}
+			if len(out) != len(tc.out) {
+				t.Fatalf("output = %v, want %v", out, tc.out)
+			}
+			for i := range out {
+				if out[i] != tc.out[i] {
+					t.Errorf("output = %v, want %v", out, tc.out)
+					break
+				}
+			}
+			for addr, want := range tc.mem {
+				if got := c.Peek(addr); got.Cmp(big.NewInt(int64(want))) != 0 {
+					t.Errorf("mem[%d] = %s, want %d", addr, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBigValues(t *testing.T) {
+	tests := []struct {
+		name string
+		prog string
+		in   []string
+		want string
+	}{
+		{name: "mul-past-int64", prog: "1102,9223372036854775807,9223372036854775807,7,4,7,99,0",
+			want: "85070591730234615847396907784232501249"},
+		{name: "literal", prog: "104,100000000000000000000000,99", want: "100000000000000000000000"},
+		{name: "add-negative", prog: "1101,-100000000000000000000000,1,7,4,7,99,0",
+			want: "-99999999999999999999999"},
+		{name: "input", prog: "3,0,1002,0,2,0,4,0,99", in: []string{"1267650600228229401496703205376"},
+			want: "2535301200456458802993406410752"},
+		{name: "compare", prog: "1107,100000000000000000000000,100000000000000000000001,7,4,7,99,0", want: "1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var in []*big.Int
+			for _, s := range tc.in {
+				in = append(in, mustBig(t, s))
+			}
+			_, out, err := runBigProgram(t, tc.prog, in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(out) != 1 || out[0].Cmp(mustBig(t, tc.want)) != 0 {
+				t.Errorf("output = %v, want [%s]", out, tc.want)
+			}
+		})
+	}
+}
+
+func TestBigHugeAddress(t *testing.T) {
+	_, _, err := runBigProgram(t, "104,1,4,100000000000000000000000,99", nil)
+	var addr *InvalidAddressError
+	if !errors.As(err, &addr) {
+		t.Fatalf("error = %v, want an InvalidAddressError", err)
+	}
+	if addr.IP != 2 {
+		t.Errorf("fault at ip=%d, want 2", addr.IP)
+	}
+}
+
+func TestBigPeekCopies(t *testing.T) {
+	c := NewBig("test", []*big.Int{big.NewInt(99), big.NewInt(5)})
+	c.Peek(1).SetInt64(6)
+	if got := c.Peek(1); got.Int64() != 5 {
+		t.Errorf("mem[1] = %s after changing a peeked value, want 5", got)
+	}
+}
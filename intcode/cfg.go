@@ -0,0 +1,250 @@
+package intcode
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Block is a basic block: a run of instructions entered only at its first
+// instruction and left only after its last.
+type Block struct {
+	Start int
+	// End is the address just past the last instruction.
+	End   int
+	Insts []Instruction
+	// Succs holds the start addresses of the blocks control may pass to.
+	Succs []int
+	// Call is the entry of the subroutine the block calls, and Return the
+	// address execution continues at once it returns; both are -1 when the
+	// block does not end in a call.
+	Call   int
+	Return int
+	// Indirect is set when the block ends in a jump through memory, whose
+	// target cannot be known statically. Subroutine returns look like this.
+	Indirect bool
+	Halts    bool
+	// Sub is the entry address of the subroutine the block belongs to; the
+	// main program has entry 0.
+	Sub int
+}
+
+// CFG is the control-flow graph of a program.
+type CFG struct {
+	Listing *Listing
+	Blocks  map[int]*Block
+	// Subs maps each subroutine entry to the starts of its blocks, in
+	// address order. Entry 0 is the main program.
+	Subs map[int][]int
+}
+
+// BuildCFG splits the code found by Disassemble into basic blocks.
+//
+// Blocks end at jumps and halts, and start at jump targets and the addresses
+// following jumps. Intcode has no call instruction; a call is recognized as an
+// immediate return address pushed onto the relative-base stack directly
+// followed by an unconditional jump, as the profiler does. Each called address
+// starts a subroutine, which owns the blocks reachable from it without
+// following calls.
+func BuildCFG(mem []int) *CFG {
+	l := Disassemble(mem)
+	g := &CFG{
+		Listing: l,
+		Blocks:  map[int]*Block{},
+		Subs:    map[int][]int{},
+	}
+
+	addrs := make([]int, 0, len(l.Code))
+	for addr := range l.Code {
+		addrs = append(addrs, addr)
+	}
+	sort.Ints(addrs)
+
+	leaders := map[int]bool{0: true}
+	for label := range l.Labels {
+		leaders[label] = true
+	}
+	for _, inst := range l.Code {
+		if inst.Jump() || inst.Opcode == OpcodeDie {
+			leaders[inst.Addr+inst.Len()] = true
+		}
+	}
+
+	var b *Block
+	for _, addr := range addrs {
+		inst := l.Code[addr]
+		if b == nil || leaders[addr] || b.End != addr {
+			b = &Block{Start: addr, End: addr, Call: -1, Return: -1}
+			g.Blocks[addr] = b
+		}
+		b.Insts = append(b.Insts, inst)
+		b.End = addr + inst.Len()
+		if inst.Jump() || inst.Opcode == OpcodeDie {
+			b = nil
+		}
+	}
+
+	for _, b := range g.Blocks {
+		g.link(b)
+	}
+	g.assignSubs()
+	return g
+}
+
+func (g *CFG) link(b *Block) {
+	last := b.Insts[len(b.Insts)-1]
+	next := b.End
+	_, fallsThrough := g.Blocks[next]
+
+	switch {
+	case last.Opcode == OpcodeDie:
+		b.Halts = true
+		return
+	case !last.Jump():
+		if fallsThrough {
+			b.Succs = append(b.Succs, next)
+		}
+		return
+	}
+
+	taken, known := last.decided()
+	if t, ok := last.Target(); ok {
+		if known && taken && len(b.Insts) > 1 {
+			if v, ok := b.Insts[len(b.Insts)-2].pushedConst(); ok && v == next {
+				b.Call = t
+				b.Return = next
+			}
+		}
+		if _, ok := g.Blocks[t]; ok && (taken || !known) {
+			b.Succs = append(b.Succs, t)
+		}
+	} else if taken || !known {
+		b.Indirect = true
+	}
+	if fallsThrough && (!taken || !known) {
+		b.Succs = append(b.Succs, next)
+	}
+}
+
+// assignSubs gives every block to the first subroutine, in address order,
+// that reaches it without following calls. Returns are followed from the call
+// site, so the code after a call stays with the caller.
+func (g *CFG) assignSubs() {
+	entries := []int{0}
+	for _, b := range g.Blocks {
+		if b.Call >= 0 {
+			if _, ok := g.Blocks[b.Call]; ok {
+				entries = append(entries, b.Call)
+			}
+		}
+	}
+	sort.Ints(entries)
+
+	seen := map[int]bool{}
+	for _, entry := range entries {
+		if seen[entry] {
+			continue
+		}
+		var owned []int
+		work := []int{entry}
+		for len(work) > 0 {
+			addr := work[len(work)-1]
+			work = work[:len(work)-1]
+			b, ok := g.Blocks[addr]
+			if !ok || seen[addr] {
+				continue
+			}
+			seen[addr] = true
+			b.Sub = entry
+			owned = append(owned, addr)
+			for _, s := range b.Succs {
+				if s != b.Call {
+					work = append(work, s)
+				}
+			}
+			if b.Return >= 0 {
+				work = append(work, b.Return)
+			}
+		}
+		sort.Ints(owned)
+		g.Subs[entry] = owned
+	}
+}
+
+// SubName returns the name used for the subroutine entered at entry.
+func SubName(entry int) string {
+	if entry == 0 {
+		return "main"
+	}
+	return fmt.Sprintf("sub_%d", entry)
+}
+
+// WriteDOT writes the graph in Graphviz DOT format, with each subroutine in
+// its own cluster. Calls are dashed edges and returns dotted ones; blocks
+// ending in an indirect jump are drawn in red.
+func (g *CFG) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "digraph intcode {")
+	fmt.Fprintln(bw, "\tnode [shape=box fontname=\"monospace\"];")
+
+	entries := make([]int, 0, len(g.Subs))
+	for entry := range g.Subs {
+		entries = append(entries, entry)
+	}
+	sort.Ints(entries)
+
+	for _, entry := range entries {
+		fmt.Fprintf(bw, "\tsubgraph cluster_%s {\n", SubName(entry))
+		fmt.Fprintf(bw, "\t\tlabel=%q;\n", SubName(entry))
+		for _, start := range g.Subs[entry] {
+			b := g.Blocks[start]
+			attrs := ""
+			switch {
+			case b.Indirect:
+				attrs = " color=red"
+			case b.Halts:
+				attrs = " peripheries=2"
+			}
+			fmt.Fprintf(bw, "\t\tb%d [label=\"%s\"%s];\n", start, g.blockLabel(b), attrs)
+		}
+		fmt.Fprintln(bw, "\t}")
+	}
+
+	starts := make([]int, 0, len(g.Blocks))
+	for start := range g.Blocks {
+		starts = append(starts, start)
+	}
+	sort.Ints(starts)
+	for _, start := range starts {
+		b := g.Blocks[start]
+		for _, s := range b.Succs {
+			if s == b.Call {
+				fmt.Fprintf(bw, "\tb%d -> b%d [style=dashed label=\"call\"];\n", start, s)
+			} else {
+				fmt.Fprintf(bw, "\tb%d -> b%d;\n", start, s)
+			}
+		}
+		if b.Return >= 0 {
+			if _, ok := g.Blocks[b.Return]; ok {
+				fmt.Fprintf(bw, "\tb%d -> b%d [style=dotted label=\"return\"];\n", start, b.Return)
+			}
+		}
+	}
+
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+func (g *CFG) blockLabel(b *Block) string {
+	var sb strings.Builder
+	if label, ok := g.Listing.Labels[b.Start]; ok {
+		sb.WriteString(label + ":\\l")
+	}
+	for _, inst := range b.Insts {
+		fmt.Fprintf(&sb, "%d: %s\\l", inst.Addr, inst.format(g.Listing.Labels))
+	}
+	return sb.String()
+}
@@ -0,0 +1,58 @@
+package intcode
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildCFG(t *testing.T) {
+	mem, err := Assemble(`
+		in [x]
+		jf [x], #skip
+		add #ret, #0, rb+0
+		jt #1, #sub
+	ret:  out [x]
+	skip: hlt
+	sub:  add [x], #1, [x]
+		jt #1, rb+0
+	x:    .data 0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := BuildCFG(mem)
+
+	type shape struct {
+		End      int
+		Len      int
+		Succs    []int
+		Call     int
+		Return   int
+		Indirect bool
+		Halts    bool
+		Sub      int
+	}
+	want := map[int]shape{
+		// a conditional jump to an immediate target goes both ways
+		0: {End: 5, Len: 2, Succs: []int{14, 5}, Call: -1, Return: -1},
+		// a pushed return address then a jump is a call
+		5:  {End: 12, Len: 2, Succs: []int{15}, Call: 15, Return: 12},
+		12: {End: 14, Len: 1, Succs: []int{14}, Call: -1, Return: -1},
+		14: {End: 15, Len: 1, Call: -1, Return: -1, Halts: true},
+		// returning jumps through memory
+		15: {End: 22, Len: 2, Call: -1, Return: -1, Indirect: true, Sub: 15},
+	}
+	got := map[int]shape{}
+	for start, b := range g.Blocks {
+		if b.Start != start {
+			t.Errorf("block at %d starts at %d", start, b.Start)
+		}
+		got[start] = shape{End: b.End, Len: len(b.Insts), Succs: b.Succs, Call: b.Call, Return: b.Return,
+			Indirect: b.Indirect, Halts: b.Halts, Sub: b.Sub}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("blocks\n%+v\nwant\n%+v", got, want)
+	}
+	if want := map[int][]int{0: {0, 5, 12, 14}, 15: {15}}; !reflect.DeepEqual(g.Subs, want) {
+		t.Errorf("subroutines %v, want %v", g.Subs, want)
+	}
+}
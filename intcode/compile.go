@@ -0,0 +1,476 @@
+package intcode
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"unicode"
+)
+
+// CompileOptions control the Go source generated by Compile.
+type CompileOptions struct {
+	// Package is the package clause of the generated file.
+	Package string
+	// Type names the generated machine type.
+	Type string
+	// Patch lists addresses the caller rewrites before running the program,
+	// such as the day 2 noun and verb. Parameters stored there are read from
+	// memory at run time instead of being compiled in.
+	Patch []int
+	// Source describes where the program came from, for the file header.
+	Source string
+}
+
+// Compile translates the program image mem into Go source and writes it to w.
+//
+// The generated type embeds a *Computer and replaces its Run, RunChan and
+// Copy methods, so it is driven exactly like an interpreted machine. Its other
+// methods that execute or change memory, such as Step and Poke, go to the
+// interpreter and mark the compiled code for verification before it next
+// runs.
+//
+// Each instruction found by Disassemble becomes a labeled block of
+// straight-line Go, and jumps become gotos. Anything the compiled code cannot
+// handle falls back to the interpreter, which also reports any error: jumps to
+// addresses that were not compiled, invalid addresses, writes through
+// immediate parameters, and machines with a tracer, profiler, journal,
+// recorder, extension registry, budget or checked arithmetic.
+//
+// Programs may write into their own code. Words written through a constant
+// address are compared against the image by their instruction before it runs,
+// which interprets it if they changed. Any other write into the code switches
+// the machine to the interpreter for good.
+func Compile(w io.Writer, mem []int, opts CompileOptions) error {
+	if opts.Package == "" {
+		opts.Package = "main"
+	}
+	if opts.Type == "" {
+		return fmt.Errorf("missing type name")
+	}
+
+	l := Disassemble(mem)
+	addrs := make([]int, 0, len(l.Code))
+	for addr := range l.Code {
+		addrs = append(addrs, addr)
+	}
+	sort.Ints(addrs)
+
+	patch := map[int]bool{}
+	for _, addr := range opts.Patch {
+		if _, ok := l.Code[addr]; ok {
+			return fmt.Errorf("cannot patch instruction word at %d", addr)
+		}
+		patch[addr] = true
+	}
+
+	code := make([]bool, len(mem))
+	for _, inst := range l.Code {
+		for a := inst.Addr; a < inst.Addr+inst.Len(); a++ {
+			code[a] = !patch[a]
+		}
+	}
+	// Code words written through a constant address are checked by the
+	// instructions they belong to instead of invalidating the whole program.
+	guard := map[int]bool{}
+	for _, inst := range l.Code {
+		j := len(inst.Params) - 1
+		if !inst.writes() || inst.Modes[j] != ModePosition || patch[inst.Addr+1+j] {
+			continue
+		}
+		if d := inst.Params[j]; d >= 0 && d < len(code) && code[d] {
+			guard[d] = true
+			code[d] = false
+		}
+	}
+
+	g := &compiler{
+		buf:     &bytes.Buffer{},
+		listing: l,
+		code:    code,
+		patch:   patch,
+		guard:   guard,
+		t:       opts.Type,
+		vars:    lowerFirst(opts.Type),
+	}
+
+	g.printf("// Code generated by cmd/compile from %s; DO NOT EDIT.\n\n", opts.Source)
+	g.printf("package %s\n\n", opts.Package)
+	g.printf("import (\n\"context\"\n\n\"github.com/eaceaser/advent-2019/intcode\"\n)\n\n")
+	g.header()
+	g.run(addrs)
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("formatting generated code: %v", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+type compiler struct {
+	buf     *bytes.Buffer
+	listing *Listing
+	code    []bool
+	patch   map[int]bool
+	guard   map[int]bool
+	t       string
+	vars    string
+	selfmod bool
+	dynamic bool
+}
+
+func (g *compiler) printf(format string, args ...interface{}) {
+	fmt.Fprintf(g.buf, format, args...)
+}
+
+func (g *compiler) header() {
+	t, v := g.t, g.vars
+	ctor, wrap := "New"+upperFirst(t), "Wrap"+upperFirst(t)
+	if !unicode.IsUpper([]rune(t)[0]) {
+		ctor, wrap = "new"+upperFirst(t), "wrap"+upperFirst(t)
+	}
+
+	g.printf("// %sImage is the program %s was compiled from.\n", v, t)
+	g.printf("var %sImage = [...]int{", v)
+	for i, word := range g.listing.Mem {
+		if i%dataPerLine == 0 {
+			g.printf("\n")
+		}
+		g.printf("%d, ", word)
+	}
+	g.printf("\n}\n\n")
+
+	g.printf("// %sCode marks the words of the compiled instructions.\n", v)
+	g.printf("var %sCode = [%d]bool{", v, len(g.code))
+	n := 0
+	for addr, isCode := range g.code {
+		if !isCode {
+			continue
+		}
+		if n%dataPerLine == 0 {
+			g.printf("\n")
+		}
+		g.printf("%d: true, ", addr)
+		n++
+	}
+	g.printf("\n}\n\n")
+
+	g.printf(`// %[1]s runs a compiled intcode program.
+type %[1]s struct {
+	*intcode.Computer
+
+	// modified is set once the compiled code has been overwritten.
+	modified bool
+	// dirty is set when the interpreter has run since the code was verified.
+	dirty bool
+}
+
+// %[2]s returns a machine executing mem, which should be the compiled
+// image, possibly patched at the addresses given to the compiler.
+func %[2]s(name string, mem []int) *%[1]s {
+	return %[4]s(intcode.New(name, mem))
+}
+
+// %[4]s returns a machine running the compiled code on c, whose memory
+// should likewise be the compiled image. The machine takes c over.
+func %[4]s(c *intcode.Computer) *%[1]s {
+	m := &%[1]s{Computer: c}
+	m.verify()
+	return m
+}
+
+// Copy returns an independent copy of the machine, including its memory.
+func (m *%[1]s) Copy() *%[1]s {
+	return &%[1]s{
+		Computer: m.Computer.Copy(),
+		modified: m.modified,
+		dirty:    m.dirty,
+	}
+}
+
+// RunChan runs the program to completion, reading input from input and
+// writing output to output. The output channel is not closed.
+func (m *%[1]s) RunChan(input <-chan int, output chan<- int) error {
+	for {
+		s, err := m.Run()
+		if err != nil {
+			return err
+		}
+
+		switch s {
+		case intcode.StateInput:
+			m.In = <-input
+		case intcode.StateOutput:
+			output <- m.Out
+		case intcode.StateHalted:
+			return nil
+		}
+	}
+}
+
+// RunContext is like Run, but interpreted, stopping when ctx is done.
+func (m *%[1]s) RunContext(ctx context.Context) (intcode.State, error) {
+	m.dirty = true
+	return m.Computer.RunContext(ctx)
+}
+
+// RunChanContext is like RunChan, but interpreted, stopping when ctx is done.
+func (m *%[1]s) RunChanContext(ctx context.Context, input <-chan int, output chan<- int) error {
+	m.dirty = true
+	return m.Computer.RunChanContext(ctx, input, output)
+}
+
+// Step executes a single instruction with the interpreter.
+func (m *%[1]s) Step() (intcode.State, error) {
+	m.dirty = true
+	return m.Computer.Step()
+}
+
+// Poke writes v to addr.
+func (m *%[1]s) Poke(addr int, v int) error {
+	m.dirty = true
+	return m.Computer.Poke(addr, v)
+}
+
+// Back steps the machine back one instruction.
+func (m *%[1]s) Back() error {
+	m.dirty = true
+	return m.Computer.Back()
+}
+
+// BackInput steps the machine back to its last input request.
+func (m *%[1]s) BackInput() error {
+	m.dirty = true
+	return m.Computer.BackInput()
+}
+
+// BackTo steps the machine back to the given step count.
+func (m *%[1]s) BackTo(step int) error {
+	m.dirty = true
+	return m.Computer.BackTo(step)
+}
+
+// verify checks that the compiled code still matches memory.
+func (m *%[1]s) verify() {
+	m.dirty = false
+	n := m.Native()
+	for addr, isCode := range %[3]sCode {
+		if v, _ := n.Load(addr); isCode && v != %[3]sImage[addr] {
+			m.modified = true
+			return
+		}
+	}
+}
+
+`, t, ctor, v, wrap)
+}
+
+func (g *compiler) run(addrs []int) {
+	// the body is generated first to learn which labels it needs
+	outer, body := g.buf, &bytes.Buffer{}
+	g.buf = body
+
+	for i, addr := range addrs {
+		inst := g.listing.Code[addr]
+		g.printf("L%d:\n", addr)
+		g.printf("// %d: %s\n", addr, inst.String())
+		for a := addr; a < addr+inst.Len(); a++ {
+			if g.guard[a] {
+				g.printf("if v, _ = n.Load(%d); v != %d {\n%s}\n", a, g.listing.Mem[a], g.fail(addr))
+			}
+		}
+		if g.instruction(inst) {
+			continue
+		}
+		next := addr + inst.Len()
+		if i+1 < len(addrs) && addrs[i+1] == next {
+			continue
+		}
+		g.printf("ip = %d\n", next)
+		g.printf("goto dispatch\n")
+		g.dynamic = true
+	}
+
+	g.buf = outer
+	g.printf(`// Run executes until the program requests input, produces output, or halts,
+// like intcode.Computer.Run.
+func (m *%s) Run() (intcode.State, error) {
+	n := m.Native()
+	if m.dirty && !m.modified {
+		m.verify()
+	}
+	if m.modified || !n.Plain() {
+		m.dirty = true
+		return m.Computer.Run()
+	}
+	if err := n.Resume(); err != nil {
+		return m.State(), err
+	}
+
+	ip, rel := n.Regs()
+	steps := 0
+	var a, b, d, p, v int
+	var ok bool
+	_, _, _, _, _, _ = a, b, d, p, v, ok
+
+`, g.t)
+	if g.dynamic {
+		g.printf("dispatch:\n")
+	}
+	g.printf("switch ip {\n")
+	for _, addr := range addrs {
+		g.printf("case %d:\ngoto L%d\n", addr, addr)
+	}
+	g.printf("}\ngoto interp\n\n")
+
+	g.buf.Write(body.Bytes())
+
+	if g.selfmod {
+		g.printf("selfmod:\nm.modified = true\n")
+	}
+	g.printf(`interp:
+	m.dirty = true
+	n.Yield(intcode.StateRunning, ip, ip, rel, 0, steps)
+	return m.Computer.Run()
+}
+`)
+}
+
+// instruction emits the code for inst and reports whether control never
+// falls through to the next instruction.
+func (g *compiler) instruction(inst Instruction) bool {
+	addr := inst.Addr
+	next := addr + inst.Len()
+
+	if j := len(inst.Params) - 1; inst.writes() && inst.Modes[j] == ModeImmediate {
+		// the interpreter reports the write to an immediate parameter
+		g.printf("%s\n", g.fail(addr))
+		return true
+	}
+
+	switch inst.Opcode {
+	case OpcodeAdd, OpcodeMultiply, OpcodeLT, OpcodeEql:
+		g.operand(inst, 0, "a")
+		g.operand(inst, 1, "b")
+		switch inst.Opcode {
+		case OpcodeAdd:
+			g.printf("v = a + b\n")
+		case OpcodeMultiply:
+			g.printf("v = a * b\n")
+		case OpcodeLT:
+			g.printf("v = 0\nif a < b {\nv = 1\n}\n")
+		case OpcodeEql:
+			g.printf("v = 0\nif a == b {\nv = 1\n}\n")
+		}
+		g.dest(inst, 2)
+		g.printf("if !n.Store(d, v) {\n%s}\n", g.fail(addr))
+		g.printf("steps++\n")
+	case OpcodeInput:
+		g.dest(inst, 0)
+		g.printf("steps++\n")
+		g.printf("n.Yield(intcode.StateInput, %d, %d, rel, d, steps)\n", addr, next)
+		g.printf("return intcode.StateInput, nil\n\n")
+		return true
+	case OpcodeOutput:
+		g.operand(inst, 0, "a")
+		g.printf("m.Out = a\n")
+		g.printf("steps++\n")
+		g.printf("n.Yield(intcode.StateOutput, %d, %d, rel, 0, steps)\n", addr, next)
+		g.printf("return intcode.StateOutput, nil\n\n")
+		return true
+	case OpcodeJmpIfT, OpcodeJmpIfF:
+		g.operand(inst, 0, "a")
+		t, static := inst.Target()
+		if static && g.patch[addr+2] {
+			static = false
+		}
+		if !static {
+			g.operand(inst, 1, "b")
+		}
+		g.printf("steps++\n")
+		cond := "a != 0"
+		if inst.Opcode == OpcodeJmpIfF {
+			cond = "a == 0"
+		}
+		g.printf("if %s {\n", cond)
+		if _, ok := g.listing.Code[t]; static && ok {
+			g.printf("goto L%d\n", t)
+		} else {
+			if static {
+				g.printf("ip = %d\n", t)
+			} else {
+				g.printf("ip = b\n")
+			}
+			g.printf("goto dispatch\n")
+			g.dynamic = true
+		}
+		g.printf("}\n")
+	case OpcodeRelAdj:
+		g.operand(inst, 0, "a")
+		g.printf("rel += a\n")
+		g.printf("steps++\n")
+	case OpcodeDie:
+		g.printf("steps++\n")
+		g.printf("n.Yield(intcode.StateHalted, %d, %d, rel, 0, steps)\n", addr, next)
+		g.printf("return intcode.StateHalted, nil\n\n")
+		return true
+	}
+	g.printf("\n")
+	return false
+}
+
+func (g *compiler) fail(addr int) string {
+	return fmt.Sprintf("ip = %d\ngoto interp\n", addr)
+}
+
+// raw returns an expression for the j-th parameter word of inst.
+func (g *compiler) raw(inst Instruction, j int) string {
+	word := inst.Addr + 1 + j
+	if !g.patch[word] {
+		return fmt.Sprint(inst.Params[j])
+	}
+	g.printf("if p, ok = n.Load(%d); !ok {\n%s}\n", word, g.fail(inst.Addr))
+	return "p"
+}
+
+func (g *compiler) operand(inst Instruction, j int, dst string) {
+	raw := g.raw(inst, j)
+	switch inst.Modes[j] {
+	case ModeImmediate:
+		g.printf("%s = %s\n", dst, raw)
+	case ModePosition:
+		g.printf("if %s, ok = n.Load(%s); !ok {\n%s}\n", dst, raw, g.fail(inst.Addr))
+	case ModeRelative:
+		g.printf("if %s, ok = n.Load(rel + %s); !ok {\n%s}\n", dst, raw, g.fail(inst.Addr))
+	}
+}
+
+// dest stores the j-th parameter's address in d. Writes into unguarded code
+// are left to the interpreter.
+func (g *compiler) dest(inst Instruction, j int) {
+	raw := g.raw(inst, j)
+	if inst.Modes[j] == ModeRelative {
+		raw = "rel + " + raw
+	}
+	g.printf("d = %s\n", raw)
+	if inst.Modes[j] == ModePosition && !g.patch[inst.Addr+1+j] {
+		// never unguarded code; see Compile
+		return
+	}
+	g.printf("if uint(d) < uint(len(%sCode)) && %sCode[d] {\nip = %d\ngoto selfmod\n}\n", g.vars, g.vars, inst.Addr)
+	g.selfmod = true
+}
+
+func lowerFirst(s string) string {
+	r := []rune(s)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
+
+func upperFirst(s string) string {
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
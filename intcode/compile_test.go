@@ -0,0 +1,236 @@
+package intcode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// compileCase is a program run both compiled and interpreted.
+type compileCase struct {
+	name  string
+	mem   []int
+	pokes map[int]int
+	in    []int
+}
+
+// compileFaults make the interpreter fail, so the compiled code must leave
+// them to it.
+var compileFaults = []struct {
+	name string
+	prog string
+	in   []int
+}{
+	{name: "write-immediate", prog: "11101,1,2,7,4,7,99,0"},
+	{name: "input-immediate", prog: "103,5,4,5,99,0", in: []int{3}},
+	{name: "read-negative", prog: "4,-1,99"},
+	{name: "write-negative", prog: "1101,1,1,-1,99"},
+	{name: "write-past-limit", prog: "1101,1,1,5000,99"},
+	{name: "input-past-limit", prog: "3,5000,99", in: []int{1}},
+	{name: "relative-negative", prog: "109,-10,204,0,99"},
+	{name: "jump-negative", prog: "1105,1,-3"},
+	{name: "illegal-opcode", prog: "104,1,42"},
+	{name: "jump-into-data", prog: "1105,1,5,0,0,7"},
+}
+
+// compileDriver runs each compiled case like runState and prints the
+// outcomes as JSON. The cases are filled in with one constructor per case.
+const compileDriver = `package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/eaceaser/advent-2019/intcode"
+)
+
+type outcome struct {
+	Outputs []int
+	State   intcode.State
+	Err     string
+	Steps   int
+	Mem     []int
+}
+
+type machine interface {
+	Run() (intcode.State, error)
+	Poke(addr int, v int) error
+}
+
+type compileCase struct {
+	m     machine
+	c     *intcode.Computer
+	pokes map[int]int
+	in    []int
+}
+
+func run(tc compileCase) outcome {
+	for addr, v := range tc.pokes {
+		if err := tc.m.Poke(addr, v); err != nil {
+			panic(err)
+		}
+	}
+	tc.c.SetMemLimit(%[1]d)
+	var o outcome
+	var err error
+	for n := 0; ; {
+		var s intcode.State
+		if s, err = tc.m.Run(); err != nil || s == intcode.StateHalted {
+			break
+		}
+		if s == intcode.StateOutput {
+			o.Outputs = append(o.Outputs, tc.c.Out)
+			continue
+		}
+		tc.c.In = 0
+		if n < len(tc.in) {
+			tc.c.In = tc.in[n]
+		}
+		n++
+	}
+	if f, ok := intcode.FaultOf(err); ok {
+		o.Err = fmt.Sprintf("%%T at ip=%%d step=%%d", err, f.IP, f.Step)
+	} else if err != nil {
+		o.Err = err.Error()
+	}
+	o.State = tc.c.State()
+	o.Steps = tc.c.Steps()
+	o.Mem = make([]int, %[1]d)
+	for i := range o.Mem {
+		o.Mem[i] = tc.c.Peek(i)
+	}
+	return o
+}
+
+func main() {
+	var outcomes []outcome
+	for _, tc := range cases() {
+		outcomes = append(outcomes, run(tc))
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(outcomes); err != nil {
+		panic(err)
+	}
+}
+
+func cases() []compileCase {
+	var cs []compileCase
+%[2]s	return cs
+}
+`
+
+// runCompiled compiles every case into one program, runs it, and returns the
+// outcomes in order.
+func runCompiled(t *testing.T, cases []compileCase) []outcome {
+	t.Helper()
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("no go command to build generated code")
+	}
+	root, err := filepath.Abs("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	gomod := fmt.Sprintf("module compiletest\n\ngo 1.18\n\nrequire github.com/eaceaser/advent-2019 v0.0.0\n\nreplace github.com/eaceaser/advent-2019 => %s\n", root)
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var table bytes.Buffer
+	for i, tc := range cases {
+		typ := fmt.Sprintf("prog%d", i)
+		var src bytes.Buffer
+		if err := Compile(&src, tc.mem, CompileOptions{Type: typ, Source: tc.name}); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, typ+".go"), src.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&table, "\t{\n\t\tm := new%s(%q, %sImage[:])\n", upperFirst(typ), tc.name, typ)
+		fmt.Fprintf(&table, "\t\tcs = append(cs, compileCase{m, m.Computer, %#v, %#v})\n\t}\n", tc.pokes, tc.in)
+	}
+	driver := fmt.Sprintf(compileDriver, fuzzMemLimit, table.String())
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(driver), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(gobin, "run", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=-mod=mod")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("running compiled cases: %v\n%s", err, stderr.String())
+	}
+	var outcomes []outcome
+	if err := json.Unmarshal(out, &outcomes); err != nil {
+		t.Fatal(err)
+	}
+	if len(outcomes) != len(cases) {
+		t.Fatalf("%d outcomes for %d cases", len(outcomes), len(cases))
+	}
+	return outcomes
+}
+
+// runInterpreted runs a case with Run on a plain computer, as the compiled
+// code expects.
+func runInterpreted(tc compileCase) outcome {
+	c := New("interp", tc.mem)
+	for addr, v := range tc.pokes {
+		c.Poke(addr, v)
+	}
+	c.SetMemLimit(fuzzMemLimit)
+	var o outcome
+	for n := 0; ; {
+		s, err := c.Run()
+		if err != nil || s == StateHalted {
+			return finish(c, o, err)
+		}
+		if s == StateOutput {
+			o.Outputs = append(o.Outputs, c.Out)
+			continue
+		}
+		c.In = fuzzInput(tc.in, n)
+		n++
+	}
+}
+
+// compareCompiled fails for every case whose compiled run differs from Run.
+func compareCompiled(t *testing.T, cases []compileCase) {
+	t.Helper()
+	got := runCompiled(t, cases)
+	for i, tc := range cases {
+		if want := runInterpreted(tc); !reflect.DeepEqual(got[i], want) {
+			t.Errorf("%s: compiled run diverges from Run on %v\n got: %s\nwant: %s", tc.name, tc.mem, got[i], want)
+		}
+	}
+}
+
+func TestCompileMatchesRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds generated code")
+	}
+	var cases []compileCase
+	for _, tc := range conformance {
+		mem, err := Parse(tc.prog)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cases = append(cases, compileCase{name: tc.name, mem: mem, pokes: tc.pokes, in: tc.in})
+	}
+	for _, tc := range compileFaults {
+		mem, err := Parse(tc.prog)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cases = append(cases, compileCase{name: "fault/" + tc.name, mem: mem, in: tc.in})
+	}
+	compareCompiled(t, cases)
+}
@@ -0,0 +1,143 @@
+package intcode
+
+import (
+	"reflect"
+	"testing"
+)
+
+// conformance holds the sample programs published with the puzzles.
+var conformance = []struct {
+	name  string
+	prog  string
+	pokes map[int]int
+	in    []int
+	out   []int
+	// mem is the expected start of memory after the program halts.
+	mem []int
+}{
+	// day 2
+	{name: "day2/example", prog: "1,9,10,3,2,3,11,0,99,30,40,50",
+		mem: []int{3500, 9, 10, 70, 2, 3, 11, 0, 99, 30, 40, 50}},
+	{name: "day2/add", prog: "1,0,0,0,99", mem: []int{2, 0, 0, 0, 99}},
+	{name: "day2/mul", prog: "2,3,0,3,99", mem: []int{2, 3, 0, 6, 99}},
+	{name: "day2/mul-past-halt", prog: "2,4,4,5,99,0", mem: []int{2, 4, 4, 5, 99, 9801}},
+	{name: "day2/self-modifying", prog: "1,1,1,4,99,5,6,0,99", mem: []int{30, 1, 1, 4, 2, 5, 6, 0, 99}},
+
+	// day 5
+	{name: "day5/echo", prog: "3,0,4,0,99", in: []int{42}, out: []int{42}},
+	{name: "day5/modes", prog: "1002,4,3,4,33", mem: []int{1002, 4, 3, 4, 99}},
+	{name: "day5/negative", prog: "1101,100,-1,4,0", mem: []int{1101, 100, -1, 4, 99}},
+	{name: "day5/eq8-position/8", prog: "3,9,8,9,10,9,4,9,99,-1,8", in: []int{8}, out: []int{1}},
+	{name: "day5/eq8-position/7", prog: "3,9,8,9,10,9,4,9,99,-1,8", in: []int{7}, out: []int{0}},
+	{name: "day5/lt8-position/7", prog: "3,9,7,9,10,9,4,9,99,-1,8", in: []int{7}, out: []int{1}},
+	{name: "day5/lt8-position/8", prog: "3,9,7,9,10,9,4,9,99,-1,8", in: []int{8}, out: []int{0}},
+	{name: "day5/eq8-immediate/8", prog: "3,3,1108,-1,8,3,4,3,99", in: []int{8}, out: []int{1}},
+	{name: "day5/eq8-immediate/9", prog: "3,3,1108,-1,8,3,4,3,99", in: []int{9}, out: []int{0}},
+	{name: "day5/lt8-immediate/-3", prog: "3,3,1107,-1,8,3,4,3,99", in: []int{-3}, out: []int{1}},
+	{name: "day5/lt8-immediate/8", prog: "3,3,1107,-1,8,3,4,3,99", in: []int{8}, out: []int{0}},
+	{name: "day5/jump-position/0", prog: "3,12,6,12,15,1,13,14,13,4,13,99,-1,0,1,9", in: []int{0}, out: []int{0}},
+	{name: "day5/jump-position/5", prog: "3,12,6,12,15,1,13,14,13,4,13,99,-1,0,1,9", in: []int{5}, out: []int{1}},
+	{name: "day5/jump-immediate/0", prog: "3,3,1105,-1,9,1101,0,0,12,4,12,99,1", in: []int{0}, out: []int{0}},
+	{name: "day5/jump-immediate/5", prog: "3,3,1105,-1,9,1101,0,0,12,4,12,99,1", in: []int{5}, out: []int{1}},
+	{name: "day5/cmp8/7", prog: day5Cmp8, in: []int{7}, out: []int{999}},
+	{name: "day5/cmp8/8", prog: day5Cmp8, in: []int{8}, out: []int{1000}},
+	{name: "day5/cmp8/9", prog: day5Cmp8, in: []int{9}, out: []int{1001}},
+
+	// day 9
+	{name: "day9/quine", prog: day9Quine,
+		out: []int{109, 1, 204, -1, 1001, 100, 1, 100, 1008, 100, 16, 101, 1006, 101, 0, 99}},
+	{name: "day9/16-digit", prog: "1102,34915192,34915192,7,4,7,99,0", out: []int{1219070632396864}},
+	{name: "day9/large", prog: "104,1125899906842624,99", out: []int{1125899906842624}},
+	{name: "day9/relative-base", prog: "109,2000,109,19,204,-34,99", pokes: map[int]int{1985: 77}, out: []int{77}},
+	{name: "day9/relative-input", prog: "109,10,203,0,204,0,99", in: []int{-5}, out: []int{-5}},
+}
+
+const (
+	day5Cmp8 = "3,21,1008,21,8,20,1005,20,22,107,8,21,20,1006,20,31,1106,0,36,98,0,0," +
+		"1002,21,125,20,4,20,1105,1,46,104,999,1105,1,46,1101,1000,1,20,4,20,1105,1,46,98,99"
+	day9Quine = "109,1,204,-1,1001,100,1,100,1008,100,16,101,1006,101,0,99"
+)
+
+// driveRun runs c with Run, feeding it in.
+func driveRun(c *Computer, in []int) ([]int, error) {
+	var out []int
+	for {
+		s, err := c.Run()
+		if err != nil {
+			return out, err
+		}
+		switch s {
+		case StateInput:
+			c.In, in = in[0], in[1:]
+		case StateOutput:
+			out = append(out, c.Out)
+		case StateHalted:
+			return out, nil
+		}
+	}
+}
+
+// driveChan runs c with RunChan, feeding it in.
+func driveChan(c *Computer, in []int) ([]int, error) {
+	input := make(chan int, len(in))
+	for _, v := range in {
+		input <- v
+	}
+	output := make(chan int)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- c.RunChan(input, output)
+		close(output)
+	}()
+
+	var out []int
+	for v := range output {
+		out = append(out, v)
+	}
+	return out, <-errs
+}
+
+func TestConformance(t *testing.T) {
+	drivers := []struct {
+		name  string
+		drive func(c *Computer, in []int) ([]int, error)
+	}{
+		{"Run", driveRun},
+		{"RunChan", driveChan},
+	}
+
+	for _, tc := range conformance {
+		for _, d := range drivers {
+			t.Run(tc.name+"/"+d.name, func(t *testing.T) {
+				mem, err := Parse(tc.prog)
+				if err != nil {
+					t.Fatal(err)
+				}
+				c := New("conformance", mem)
+				for addr, v := range tc.pokes {
+					if err := c.Poke(addr, v); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				out, err := d.drive(c, tc.in)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if c.State() != StateHalted {
+					t.Errorf("state = %s, want %s", c.State(), StateHalted)
+				}
+				if len(out) != 0 || len(tc.out) != 0 {
+					if !reflect.DeepEqual(out, tc.out) {
+						t.Errorf("output = %v, want %v", out, tc.out)
+					}
+				}
+				for addr, want := range tc.mem {
+					if got := c.Peek(addr); got != want {
+						t.Errorf("mem[%d] = %d, want %d", addr, got, want)
+					}
+				}
+			})
+		}
+	}
+}
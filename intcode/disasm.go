@@ -0,0 +1,364 @@
+package intcode
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	dataPerLine = 8
+	maxParams   = 3
+)
+
+var mnemonics = map[int]string{
+	OpcodeAdd:      "add",
+	OpcodeMultiply: "mul",
+	OpcodeInput:    "in",
+	OpcodeOutput:   "out",
+	OpcodeJmpIfT:   "jt",
+	OpcodeJmpIfF:   "jf",
+	OpcodeLT:       "lt",
+	OpcodeEql:      "eq",
+	OpcodeRelAdj:   "arb",
+	OpcodeDie:      "hlt",
+}
+
+var opcodes = func() map[string]int {
+	rv := make(map[string]int, len(mnemonics))
+	for op, m := range mnemonics {
+		rv[m] = op
+	}
+	return rv
+}()
+
+var paramCounts = map[int]int{
+	OpcodeAdd:      3,
+	OpcodeMultiply: 3,
+	OpcodeInput:    1,
+	OpcodeOutput:   1,
+	OpcodeJmpIfT:   2,
+	OpcodeJmpIfF:   2,
+	OpcodeLT:       3,
+	OpcodeEql:      3,
+	OpcodeRelAdj:   1,
+	OpcodeDie:      0,
+}
+
+// Instruction is a single decoded instruction.
+type Instruction struct {
+	Addr   int
+	Opcode int
+	Modes  []int
+	Params []int
+
+	// name is the mnemonic of an extension opcode.
+	name string
+}
+
+// Decode decodes the instruction at addr. It reports false if the word at addr
+// is not a valid instruction or its parameters run past the end of mem.
+func Decode(mem []int, addr int) (Instruction, bool) {
+	return decode(func(a int) (int, bool) {
+		if a < 0 || a >= len(mem) {
+			return 0, false
+		}
+		return mem[a], true
+	}, nil, addr)
+}
+
+// Decode decodes the instruction at addr in the computer's memory, including
+// the extension opcodes of its registry.
+func (c *Computer) Decode(addr int) (Instruction, bool) {
+	return decode(func(a int) (int, bool) {
+		v, err := c.mem.get(a)
+		return v, err == nil
+	}, c.ext, addr)
+}
+
+func decode(word func(addr int) (int, bool), r *Registry, addr int) (Instruction, bool) {
+	code, ok := word(addr)
+	if !ok {
+		return Instruction{}, false
+	}
+	opcode, modes := ParseOpcode(code)
+	n, name, ok := r.spec(opcode)
+	if !ok || len(modes) > n {
+		return Instruction{}, false
+	}
+	modes = pad(modes, n)
+	for _, m := range modes {
+		if m != ModePosition && m != ModeImmediate && m != ModeRelative {
+			return Instruction{}, false
+		}
+	}
+	params := make([]int, n)
+	for i := range params {
+		if params[i], ok = word(addr + 1 + i); !ok {
+			return Instruction{}, false
+		}
+	}
+	inst := Instruction{
+		Addr:   addr,
+		Opcode: opcode,
+		Modes:  modes,
+		Params: params,
+	}
+	if _, builtin := paramCounts[opcode]; !builtin {
+		inst.name = name
+	}
+	return inst, true
+}
+
+// Opcode returns the opcode for an assembly mnemonic.
+func Opcode(mnemonic string) (int, bool) {
+	op, ok := opcodes[mnemonic]
+	return op, ok
+}
+
+// Len returns the number of words the instruction occupies.
+func (i Instruction) Len() int {
+	return len(i.Params) + 1
+}
+
+// Words returns the raw words of the instruction.
+func (i Instruction) Words() []int {
+	rv := make([]int, 0, i.Len())
+	code := i.Opcode
+	for j := len(i.Modes) - 1; j >= 0; j-- {
+		code += i.Modes[j] * pow10(j+2)
+	}
+	rv = append(rv, code)
+	return append(rv, i.Params...)
+}
+
+// Mnemonic returns the instruction's assembly mnemonic.
+func (i Instruction) Mnemonic() string {
+	if i.name != "" {
+		return i.name
+	}
+	return mnemonics[i.Opcode]
+}
+
+// Jump reports whether the instruction is a conditional jump.
+func (i Instruction) Jump() bool {
+	return i.Opcode == OpcodeJmpIfT || i.Opcode == OpcodeJmpIfF
+}
+
+// Target returns the jump target of an immediate mode jump.
+func (i Instruction) Target() (int, bool) {
+	if !i.Jump() || i.Modes[1] != ModeImmediate {
+		return 0, false
+	}
+	return i.Params[1], true
+}
+
+func (i Instruction) String() string {
+	return i.format(nil)
+}
+
+func (i Instruction) format(labels map[int]string) string {
+	if len(i.Params) == 0 {
+		return i.Mnemonic()
+	}
+	ops := make([]string, len(i.Params))
+	for j, p := range i.Params {
+		ops[j] = formatParam(i.Modes[j], p)
+	}
+	if t, ok := i.Target(); ok {
+		if l, ok := labels[t]; ok {
+			ops[1] = "#" + l
+		}
+	}
+	return i.Mnemonic() + " " + strings.Join(ops, ", ")
+}
+
+func formatParam(mode int, p int) string {
+	switch mode {
+	case ModePosition:
+		return fmt.Sprintf("[%d]", p)
+	case ModeImmediate:
+		return fmt.Sprintf("#%d", p)
+	case ModeRelative:
+		if p < 0 {
+			return fmt.Sprintf("rb%d", p)
+		}
+		return fmt.Sprintf("rb+%d", p)
+	}
+	return fmt.Sprintf("?%d", p)
+}
+
+// Listing is the result of disassembling a program image.
+type Listing struct {
+	Mem    []int
+	Code   map[int]Instruction
+	Labels map[int]string
+}
+
+// Disassemble separates a program image into code and data.
+//
+// Code is found by following control flow from address 0. Immediate jump
+// targets become labels; jumps through memory cannot be followed, so immediate
+// values pushed onto the relative-base stack are also tried as entry points,
+// since that is how programs pass return addresses to subroutines. Everything
+// not reached this way is treated as data.
+func Disassemble(mem []int) *Listing {
+	l := &Listing{
+		Mem:    mem,
+		Code:   map[int]Instruction{},
+		Labels: map[int]string{},
+	}
+
+	work := []int{0}
+	for len(work) > 0 {
+		addr := work[len(work)-1]
+		work = work[:len(work)-1]
+
+		for {
+			if _, seen := l.Code[addr]; seen {
+				break
+			}
+			inst, ok := Decode(mem, addr)
+			if !ok || l.overlaps(inst) {
+				break
+			}
+			l.Code[addr] = inst
+
+			if ret, ok := inst.pushedConst(); ok && ret > 0 && ret < len(mem) {
+				if _, ok := Decode(mem, ret); ok {
+					l.Labels[ret] = fmt.Sprintf("L%d", ret)
+					work = append(work, ret)
+				}
+			}
+
+			if inst.Opcode == OpcodeDie {
+				break
+			}
+			if inst.Jump() {
+				if t, ok := inst.Target(); ok {
+					l.Labels[t] = fmt.Sprintf("L%d", t)
+					work = append(work, t)
+				}
+				if taken, known := inst.decided(); known && taken {
+					break
+				}
+			}
+			addr += inst.Len()
+		}
+	}
+
+	return l
+}
+
+// pushedConst returns the value stored by an add or mul of two immediates into
+// a relative address, which is how programs push return addresses.
+func (i Instruction) pushedConst() (int, bool) {
+	if i.Opcode != OpcodeAdd && i.Opcode != OpcodeMultiply {
+		return 0, false
+	}
+	if i.Modes[0] != ModeImmediate || i.Modes[1] != ModeImmediate || i.Modes[2] != ModeRelative {
+		return 0, false
+	}
+	if i.Opcode == OpcodeAdd {
+		v, _ := addOp(i.Params[0], i.Params[1])
+		return v, true
+	}
+	v, _ := multOp(i.Params[0], i.Params[1])
+	return v, true
+}
+
+// writes reports whether the instruction's last parameter is an address it
+// stores to.
+func (i Instruction) writes() bool {
+	switch i.Opcode {
+	case OpcodeAdd, OpcodeMultiply, OpcodeLT, OpcodeEql, OpcodeInput:
+		return true
+	}
+	return false
+}
+
+// decided reports whether a jump with an immediate condition is always taken.
+func (i Instruction) decided() (taken bool, known bool) {
+	if i.Modes[0] != ModeImmediate {
+		return false, false
+	}
+	if i.Opcode == OpcodeJmpIfT {
+		return i.Params[0] != 0, true
+	}
+	return i.Params[0] == 0, true
+}
+
+func (l *Listing) overlaps(inst Instruction) bool {
+	for a := inst.Addr + 1; a < inst.Addr+inst.Len(); a++ {
+		if _, ok := l.Code[a]; ok {
+			return true
+		}
+	}
+	for a := inst.Addr - 1; a >= 0 && a >= inst.Addr-maxParams; a-- {
+		if prev, ok := l.Code[a]; ok && a+prev.Len() > inst.Addr {
+			return true
+		}
+	}
+	return false
+}
+
+// Print writes an annotated listing of the program to w.
+func (l *Listing) Print(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	var data []int
+	dataAddr := 0
+	flush := func() {
+		for len(data) > 0 {
+			n := len(data)
+			if n > dataPerLine {
+				n = dataPerLine
+			}
+			words := make([]string, n)
+			for i, d := range data[:n] {
+				words[i] = fmt.Sprint(d)
+			}
+			fmt.Fprintf(bw, "%6d  %-24s .data %s\n", dataAddr, "", strings.Join(words, ", "))
+			data = data[n:]
+			dataAddr += n
+		}
+	}
+
+	for addr := 0; addr < len(l.Mem); {
+		label, hasLabel := l.Labels[addr]
+		inst, isCode := l.Code[addr]
+		if hasLabel || isCode {
+			flush()
+		}
+		if hasLabel {
+			fmt.Fprintf(bw, "%s:\n", label)
+		}
+		if !isCode {
+			if len(data) == 0 {
+				dataAddr = addr
+			}
+			data = append(data, l.Mem[addr])
+			addr++
+			continue
+		}
+
+		words := make([]string, inst.Len())
+		for i, word := range l.Mem[addr : addr+inst.Len()] {
+			words[i] = fmt.Sprint(word)
+		}
+		fmt.Fprintf(bw, "%6d  %-24s %s\n", addr, strings.Join(words, " "), inst.format(l.Labels))
+		addr += inst.Len()
+	}
+	flush()
+
+	return bw.Flush()
+}
+
+func pow10(n int) int {
+	rv := 1
+	for i := 0; i < n; i++ {
+		rv *= 10
+	}
+	return rv
+}
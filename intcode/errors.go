@@ -0,0 +1,166 @@
+package intcode
+
+import (
+	"errors"
+	"fmt"
+)
+
+const excerptLen = 8
+
+// Fault locates an error in a running program. It is embedded in every error
+// the VM returns while executing an instruction.
+type Fault struct {
+	// IP is the address of the faulting instruction.
+	IP int
+	// Word is the raw instruction word at IP.
+	Word int
+	// Step is the number of instructions executed before the fault.
+	Step int
+	// Excerpt holds the memory starting at IP.
+	Excerpt []int
+}
+
+func (f *Fault) fault() *Fault { return f }
+
+func (f *Fault) String() string {
+	return fmt.Sprintf("ip=%d word=%d step=%d mem=%v", f.IP, f.Word, f.Step, f.Excerpt)
+}
+
+type faulter interface {
+	error
+	fault() *Fault
+}
+
+// FaultOf returns the location err was raised at, if err is or wraps one of
+// the errors the VM returns while executing an instruction.
+func FaultOf(err error) (*Fault, bool) {
+	var f faulter
+	if !errors.As(err, &f) {
+		return nil, false
+	}
+	return f.fault(), true
+}
+
+// IllegalOpcodeError reports an instruction word with an unknown opcode.
+type IllegalOpcodeError struct {
+	Fault
+	Opcode int
+}
+
+func (e *IllegalOpcodeError) Error() string {
+	return fmt.Sprintf("illegal opcode %d (%s)", e.Opcode, &e.Fault)
+}
+
+// IllegalModeError reports an unknown parameter mode.
+type IllegalModeError struct {
+	Fault
+	Mode int
+}
+
+func (e *IllegalModeError) Error() string {
+	return fmt.Sprintf("unknown mode %d (%s)", e.Mode, &e.Fault)
+}
+
+// InvalidAddressError reports an access to a negative address or one beyond
+// the memory limit.
+type InvalidAddressError struct {
+	Fault
+	Addr  int
+	Limit int
+}
+
+func (e *InvalidAddressError) Error() string {
+	if e.Addr < 0 {
+		return fmt.Sprintf("negative address %d (%s)", e.Addr, &e.Fault)
+	}
+	return fmt.Sprintf("address %d exceeds memory limit %d (%s)", e.Addr, e.Limit, &e.Fault)
+}
+
+// WriteToImmediateError reports an output parameter in immediate mode.
+type WriteToImmediateError struct {
+	Fault
+}
+
+func (e *WriteToImmediateError) Error() string {
+	return fmt.Sprintf("output param mode cannot be immediate (%s)", &e.Fault)
+}
+
+// InvalidStateError reports an attempt to run a computer that cannot continue,
+// such as one that has halted.
+type InvalidStateError struct {
+	Fault
+	State State
+}
+
+func (e *InvalidStateError) Error() string {
+	return fmt.Sprintf("invalid state %s (%s)", e.State, &e.Fault)
+}
+
+// OverflowError reports an add or multiply whose result does not fit in an
+// int.
+type OverflowError struct {
+	Fault
+	Op string
+	A  int
+	B  int
+}
+
+func (e *OverflowError) Error() string {
+	return fmt.Sprintf("integer overflow: %s %d, %d (%s)", e.Op, e.A, e.B, &e.Fault)
+}
+
+// CanceledError reports a run stopped because its context was done. It wraps
+// the context's error, so errors.Is can tell cancellation from a timeout.
+type CanceledError struct {
+	Fault
+	Err error
+}
+
+func (e *CanceledError) Error() string {
+	return fmt.Sprintf("run canceled: %v (%s)", e.Err, &e.Fault)
+}
+
+func (e *CanceledError) Unwrap() error { return e.Err }
+
+// BudgetError reports a computer that has executed all the instructions its
+// budget allows.
+type BudgetError struct {
+	Fault
+	Budget int
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("instruction budget of %d exhausted (%s)", e.Budget, &e.Fault)
+}
+
+// HostError reports an extension opcode that failed. It wraps the error the
+// op returned.
+type HostError struct {
+	Fault
+	Op  string
+	Err error
+}
+
+func (e *HostError) Error() string {
+	return fmt.Sprintf("%s failed: %v (%s)", e.Op, e.Err, &e.Fault)
+}
+
+func (e *HostError) Unwrap() error { return e.Err }
+
+// located fills in the fault location of errors raised at the current
+// instruction.
+func (c *Computer) located(err error) error {
+	f, ok := err.(faulter)
+	if !ok {
+		return err
+	}
+	loc := f.fault()
+	loc.IP = c.opIP
+	loc.Word, _ = c.mem.get(c.opIP)
+	loc.Step = c.steps
+	loc.Excerpt = make([]int, excerptLen)
+	for i := range loc.Excerpt {
+		loc.Excerpt[i], _ = c.mem.get(c.opIP + i)
+	}
+	return err
+}
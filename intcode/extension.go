@@ -0,0 +1,179 @@
+package intcode
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// Role is how an extension opcode uses one of its parameters.
+type Role int
+
+const (
+	// RoleRead parameters are values, resolved according to their mode like
+	// the operands of add.
+	RoleRead Role = 0
+	// RoleWrite parameters are addresses the op stores a result to, like the
+	// last operand of add. They cannot be immediate.
+	RoleWrite Role = 1
+)
+
+// Op is an extension opcode, such as a host call that prints, returns random
+// numbers or reads the clock.
+type Op struct {
+	Opcode   int
+	Mnemonic string
+	Params   []Role
+
+	// Call executes the op. args holds the values of the read parameters in
+	// order, and Call returns one value per write parameter, which the VM
+	// stores in order once it returns. Call may inspect the computer but must
+	// not run it. An error stops the program with a HostError.
+	//
+	// Stepping a journaled computer back past its undo log executes ops
+	// again, so ops that are not deterministic make such history unreliable.
+	Call func(c *Computer, args []int) ([]int, error)
+}
+
+// Registry is a set of extension opcodes. The computers it is attached to
+// with SetRegistry execute its ops from the same dispatch as the built-in
+// ones, and decode and trace them by mnemonic; Assemble on the registry
+// accepts them too. Ops should all be registered before the registry is
+// used.
+type Registry struct {
+	ops    map[int]*Op
+	byName map[string]*Op
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		ops:    map[int]*Op{},
+		byName: map[string]*Op{},
+	}
+}
+
+// Register adds op to the registry. The opcode must fit in the two digits an
+// instruction word has for it, and neither it nor the mnemonic may already be
+// in use. An op takes at most three parameters.
+func (r *Registry) Register(op Op) error {
+	if op.Opcode <= 0 || op.Opcode > 99 {
+		return fmt.Errorf("opcode %d is not between 1 and 99", op.Opcode)
+	}
+	if n, name, ok := r.spec(op.Opcode); ok {
+		return fmt.Errorf("opcode %d is taken by %s/%d", op.Opcode, name, n)
+	}
+	if !validMnemonic(op.Mnemonic) {
+		return fmt.Errorf("bad mnemonic %q", op.Mnemonic)
+	}
+	if _, ok := r.opcode(op.Mnemonic); ok {
+		return fmt.Errorf("mnemonic %q is taken", op.Mnemonic)
+	}
+	if len(op.Params) > maxParams {
+		return fmt.Errorf("%s takes %d parameters, at most %d are supported", op.Mnemonic, len(op.Params), maxParams)
+	}
+	for _, role := range op.Params {
+		if role != RoleRead && role != RoleWrite {
+			return fmt.Errorf("%s has unknown parameter role %d", op.Mnemonic, role)
+		}
+	}
+	if op.Call == nil {
+		return fmt.Errorf("%s has no Call", op.Mnemonic)
+	}
+	op.Params = append([]Role(nil), op.Params...)
+	r.ops[op.Opcode] = &op
+	r.byName[op.Mnemonic] = &op
+	return nil
+}
+
+// validMnemonic reports whether m can be written in assembly: a lower case
+// letter followed by lower case letters and digits.
+func validMnemonic(m string) bool {
+	for i, ch := range m {
+		if !unicode.IsLower(ch) && (i == 0 || !unicode.IsDigit(ch)) {
+			return false
+		}
+	}
+	return m != ""
+}
+
+// SetRegistry lets the computer execute the extension opcodes in r. A nil
+// registry leaves only the built-in opcodes.
+//
+// The registry is not part of a Snapshot, so a computer restored from one
+// must have it set again. Copy shares the registry with the original.
+func (c *Computer) SetRegistry(r *Registry) {
+	c.ext = r
+}
+
+// spec returns the parameter count and mnemonic of a built-in opcode or, if r
+// is not nil, of one of its extensions.
+func (r *Registry) spec(opcode int) (int, string, bool) {
+	if n, ok := paramCounts[opcode]; ok {
+		return n, mnemonics[opcode], true
+	}
+	if r != nil {
+		if op, ok := r.ops[opcode]; ok {
+			return len(op.Params), op.Mnemonic, true
+		}
+	}
+	return 0, "", false
+}
+
+// opcode looks up a built-in or, if r is not nil, extension mnemonic.
+func (r *Registry) opcode(mnemonic string) (int, bool) {
+	if op, ok := opcodes[mnemonic]; ok {
+		return op, true
+	}
+	if r != nil {
+		if op, ok := r.byName[mnemonic]; ok {
+			return op.Opcode, true
+		}
+	}
+	return 0, false
+}
+
+// writes reports whether parameter j of opcode is written to.
+func (r *Registry) writes(opcode int, j int) bool {
+	if w, ok := writeParams[opcode]; ok {
+		return w == j
+	}
+	if r != nil {
+		if op, ok := r.ops[opcode]; ok {
+			return j < len(op.Params) && op.Params[j] == RoleWrite
+		}
+	}
+	return false
+}
+
+func (c *Computer) extImpl(op *Op, modes *[maxParams]int) error {
+	var args, dests []int
+	for j, role := range op.Params {
+		if role == RoleWrite {
+			dest, err := c.outputMode(modes[j])
+			if err != nil {
+				return err
+			}
+			dests = append(dests, dest)
+			continue
+		}
+		v, err := c.param(modes[j])
+		if err != nil {
+			return err
+		}
+		args = append(args, v)
+	}
+
+	rv, err := op.Call(c, args)
+	if err != nil {
+		return &HostError{Op: op.Mnemonic, Err: err}
+	}
+	if len(rv) != len(dests) {
+		return &HostError{Op: op.Mnemonic, Err: fmt.Errorf("returned %d values for %d write parameters", len(rv), len(dests))}
+	}
+	for j, dest := range dests {
+		if err := c.store(dest, rv[j]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
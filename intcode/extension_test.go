@@ -0,0 +1,86 @@
+package intcode
+
+import (
+	"errors"
+	"testing"
+)
+
+// newSumRegistry registers sum, which stores the sum of two values, at
+// opcode 20.
+func newSumRegistry(t *testing.T) *Registry {
+	t.Helper()
+	r := NewRegistry()
+	err := r.Register(Op{
+		Opcode:   20,
+		Mnemonic: "sum",
+		Params:   []Role{RoleRead, RoleRead, RoleWrite},
+		Call: func(c *Computer, args []int) ([]int, error) {
+			return []int{args[0] + args[1]}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestRegistryRun(t *testing.T) {
+	r := newSumRegistry(t)
+	mem, err := r.Assemble("sum #2, #40, [7]\nout [7]\nhlt\n.data 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New("ext", mem)
+	c.SetRegistry(r)
+	if s, err := c.Run(); err != nil || s != StateOutput || c.Out != 42 {
+		t.Fatalf("Run = %s, %v with output %d, want output 42", s, err, c.Out)
+	}
+
+	// a copy shares the registry
+	cp := New("ext", mem)
+	cp.SetRegistry(r)
+	if s, err := cp.Copy().Run(); err != nil || s != StateOutput {
+		t.Fatalf("copy: Run = %s, %v", s, err)
+	}
+
+	// without the registry the op is unknown
+	var illegal *IllegalOpcodeError
+	if _, err := New("plain", mem).Run(); !errors.As(err, &illegal) || illegal.Opcode != 20 {
+		t.Errorf("error without registry = %v, want illegal opcode 20", err)
+	}
+}
+
+func TestRegistryWrites(t *testing.T) {
+	r := newSumRegistry(t)
+	for j, want := range []bool{false, false, true, false, false} {
+		if got := r.writes(20, j); got != want {
+			t.Errorf("writes(20, %d) = %t, want %t", j, got, want)
+		}
+	}
+	var none *Registry
+	if none.writes(20, 2) {
+		t.Error("nil registry reports writes for an extension opcode")
+	}
+}
+
+func TestRegisterErrors(t *testing.T) {
+	call := func(c *Computer, args []int) ([]int, error) { return nil, nil }
+	tests := []struct {
+		name string
+		op   Op
+	}{
+		{"builtin-opcode", Op{Opcode: 1, Mnemonic: "x", Call: call}},
+		{"opcode-range", Op{Opcode: 100, Mnemonic: "x", Call: call}},
+		{"builtin-mnemonic", Op{Opcode: 30, Mnemonic: "add", Call: call}},
+		{"bad-mnemonic", Op{Opcode: 30, Mnemonic: "9x", Call: call}},
+		{"too-many-params", Op{Opcode: 30, Mnemonic: "x", Params: make([]Role, maxParams+1), Call: call}},
+		{"no-call", Op{Opcode: 30, Mnemonic: "x"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := NewRegistry().Register(tc.op); err == nil {
+				t.Error("Register succeeded")
+			}
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package intcode
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+const (
+	fuzzMemLimit = 1024
+	fuzzBudget   = 10000
+)
+
+// outcome is everything a run can be compared on.
+type outcome struct {
+	Outputs []int
+	State   State
+	Err     string
+	Steps   int
+	Mem     []int
+}
+
+func (o outcome) String() string {
+	return fmt.Sprintf("state=%s steps=%d err=%s outputs=%v", o.State, o.Steps, o.Err, o.Outputs)
+}
+
+// fuzzInput returns the n-th input value: the given values, then zeros.
+func fuzzInput(in []int, n int) int {
+	if n < len(in) {
+		return in[n]
+	}
+	return 0
+}
+
+func fuzzComputer(mem []int) *Computer {
+	c := New("fuzz", mem)
+	c.SetMemLimit(fuzzMemLimit)
+	c.SetArith(ArithChecked)
+	c.SetBudget(fuzzBudget)
+	return c
+}
+
+func describe(err error) string {
+	if err == nil {
+		return ""
+	}
+	f, ok := err.(faulter)
+	if !ok {
+		return err.Error()
+	}
+	return fmt.Sprintf("%T at ip=%d step=%d", err, f.fault().IP, f.fault().Step)
+}
+
+func finish(c *Computer, o outcome, err error) outcome {
+	o.Err = describe(err)
+	o.State = c.State()
+	o.Steps = c.Steps()
+	o.Mem = make([]int, fuzzMemLimit)
+	for i := range o.Mem {
+		o.Mem[i] = c.Peek(i)
+	}
+	return o
+}
+
+// runState drives the computer with Run. It also returns the error the run
+// stopped with.
+func runState(mem []int, in []int) (outcome, error) {
+	c := fuzzComputer(mem)
+	var o outcome
+	for n := 0; ; {
+		s, err := c.Run()
+		if err != nil {
+			return finish(c, o, err), err
+		}
+		switch s {
+		case StateInput:
+			c.In = fuzzInput(in, n)
+			n++
+		case StateOutput:
+			o.Outputs = append(o.Outputs, c.Out)
+		case StateHalted:
+			return finish(c, o, nil), nil
+		}
+	}
+}
+
+// runSteps drives the computer one instruction at a time, restoring it from a
+// snapshot half way through.
+func runSteps(mem []int, in []int, at int) outcome {
+	c := fuzzComputer(mem)
+	var o outcome
+	for n := 0; ; {
+		if c.Steps() == at {
+			var buf bytes.Buffer
+			if err := c.Snapshot().Write(&buf); err != nil {
+				return finish(c, o, err)
+			}
+			s, err := ReadSnapshot(&buf)
+			if err != nil {
+				return finish(c, o, err)
+			}
+			if c, err = s.Computer(); err != nil {
+				return finish(c, o, err)
+			}
+		}
+		s, err := c.Step()
+		if err != nil {
+			return finish(c, o, err)
+		}
+		switch s {
+		case StateInput:
+			c.In = fuzzInput(in, n)
+			n++
+		case StateOutput:
+			o.Outputs = append(o.Outputs, c.Out)
+		case StateHalted:
+			return finish(c, o, nil)
+		}
+	}
+}
+
+// runChan drives the computer with RunChan. The input channel is closed after
+// the given values, so the program reads zeros from then on.
+func runChan(mem []int, in []int) outcome {
+	c := fuzzComputer(mem)
+	input := make(chan int, len(in))
+	for _, v := range in {
+		input <- v
+	}
+	close(input)
+	output := make(chan int)
+
+	var o outcome
+	done := make(chan struct{})
+	go func() {
+		for v := range output {
+			o.Outputs = append(o.Outputs, v)
+		}
+		close(done)
+	}()
+	err := c.RunChanContext(context.Background(), input, output)
+	close(output)
+	<-done
+	return finish(c, o, err)
+}
+
+// runBig drives a BigComputer with Run.
+func runBig(mem []int, in []int) outcome {
+	bmem := make([]*big.Int, len(mem))
+	for i, v := range mem {
+		bmem[i] = big.NewInt(int64(v))
+	}
+	c := NewBig("fuzz", bmem)
+	c.SetMemLimit(fuzzMemLimit)
+	c.SetBudget(fuzzBudget)
+
+	var o outcome
+	var err error
+	for n := 0; ; {
+		var s State
+		if s, err = c.Run(); err != nil {
+			break
+		}
+		if s == StateInput {
+			c.In = big.NewInt(int64(fuzzInput(in, n)))
+			n++
+		} else if s == StateOutput {
+			o.Outputs = append(o.Outputs, toInt(c.Out))
+		} else {
+			break
+		}
+	}
+	o.Err = describe(err)
+	o.State = c.State()
+	o.Steps = c.Steps()
+	o.Mem = make([]int, fuzzMemLimit)
+	for i := range o.Mem {
+		o.Mem[i] = toInt(c.Peek(i))
+	}
+	return o
+}
+
+// differential runs mem on every driver and fails if any of them disagree
+// with Run.
+func differential(t *testing.T, mem []int, in []int) {
+	if len(mem) > fuzzMemLimit {
+		mem = mem[:fuzzMemLimit]
+	}
+	want, err := runState(mem, in)
+
+	check := func(name string, got outcome) {
+		t.Helper()
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s diverges from Run on %v with input %v\n got: %s\nwant: %s",
+				name, mem, in, got, want)
+		}
+	}
+	check("Step", runSteps(mem, in, want.Steps/2))
+	check("RunChan", runChan(mem, in))
+
+	// The big VM cannot overflow.
+	var overflow *OverflowError
+	if !errors.As(err, &overflow) {
+		check("BigComputer", runBig(mem, in))
+	}
+}
+
+// genProgram builds a program from fuzz data, two bytes per word. Most words
+// are well-formed instructions or small addresses so that programs get past
+// their first few instructions.
+func genProgram(data []byte) []int {
+	ops := []int{OpcodeAdd, OpcodeMultiply, OpcodeInput, OpcodeOutput,
+		OpcodeJmpIfT, OpcodeJmpIfF, OpcodeLT, OpcodeEql, OpcodeRelAdj, OpcodeDie}
+	mem := make([]int, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		hi, lo := int(data[i]), int(data[i+1])
+		switch hi % 4 {
+		case 0, 1:
+			mem = append(mem, ops[lo%len(ops)]+100*(lo/10%3)+1000*(hi/4%3)+10000*(hi/12%3))
+		case 2:
+			mem = append(mem, lo%64)
+		case 3:
+			mem = append(mem, int(int8(lo))<<uint(hi/4%48))
+		}
+	}
+	return mem
+}
+
+func genInput(data []byte) []int {
+	in := make([]int, len(data))
+	for i, b := range data {
+		in[i] = int(int8(b))
+	}
+	return in
+}
+
+// generatedSeeds hold program and input data for genProgram and genInput.
+var generatedSeeds = [][2][]byte{
+	{{0, 3, 2, 9, 0, 4, 2, 9, 0, 9}, {7}},
+	{{0, 21, 2, 5, 2, 6, 2, 0, 0, 9, 3, 1, 3, 2}, {}},
+	{{4, 5, 2, 1, 2, 0, 0, 3, 2, 9, 0, 4, 2, 9, 0, 9}, {0, 1}},
+}
+
+// imageSeeds are the published sample programs with an input.
+var imageSeeds = [][2]string{
+	{"3,9,8,9,10,9,4,9,99,-1,8", "8"},
+	{"3,3,1105,-1,9,1101,0,0,12,4,12,99,1", "0"},
+	{"109,1,204,-1,1001,100,1,100,1008,100,16,101,1006,101,0,99", ""},
+	{"1102,34915192,34915192,7,4,7,99,0", ""},
+	{"104,1125899906842624,99", ""},
+	{"1,9,10,3,2,3,11,0,99,30,40,50", ""},
+}
+
+// FuzzGenerated compares the drivers on generated programs. Compiled code is
+// not fuzzed, since every program has to be built with the go command;
+// TestCompileGenerated compares it on a fixed batch of generated programs.
+func FuzzGenerated(f *testing.F) {
+	for _, seed := range generatedSeeds {
+		f.Add(seed[0], seed[1])
+	}
+	f.Fuzz(func(t *testing.T, prog []byte, input []byte) {
+		differential(t, genProgram(prog), genInput(input))
+	})
+}
+
+// FuzzImage compares the drivers on raw program images, seeded with the
+// published sample programs. Like FuzzGenerated, it leaves compiled code out.
+func FuzzImage(f *testing.F) {
+	for _, seed := range imageSeeds {
+		f.Add(seed[0], seed[1])
+	}
+	f.Fuzz(func(t *testing.T, prog string, input string) {
+		mem, err := Parse(prog)
+		if err != nil {
+			t.Skip()
+		}
+		in, err := Parse(input)
+		if err != nil || input == "" {
+			in = nil
+		}
+		differential(t, mem, in)
+	})
+}
+
+// TestCompileGenerated compares compiled code with Run on the fuzz seeds and
+// on programs generated from fixed pseudo-random data. Programs that do not
+// finish within the fuzz budget are left out, as compiled code cannot be
+// stopped.
+func TestCompileGenerated(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds generated code")
+	}
+	var cases []compileCase
+	add := func(name string, mem []int, in []int) {
+		if len(mem) > fuzzMemLimit {
+			mem = mem[:fuzzMemLimit]
+		}
+		if finishes(mem, in) {
+			cases = append(cases, compileCase{name: name, mem: mem, in: in})
+		}
+	}
+	for i, seed := range generatedSeeds {
+		add(fmt.Sprintf("generated/seed%d", i), genProgram(seed[0]), genInput(seed[1]))
+	}
+	for i, seed := range imageSeeds {
+		mem, err := Parse(seed[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		in, _ := Parse(seed[1])
+		if seed[1] == "" {
+			in = nil
+		}
+		add(fmt.Sprintf("image/seed%d", i), mem, in)
+	}
+	r := rand.New(rand.NewSource(2019))
+	for i := 0; len(cases) < 100; i++ {
+		prog, input := make([]byte, 4+r.Intn(120)), make([]byte, r.Intn(8))
+		r.Read(prog)
+		r.Read(input)
+		add(fmt.Sprintf("generated/%d", i), genProgram(prog), genInput(input))
+	}
+	compareCompiled(t, cases)
+}
+
+// finishes reports whether mem halts or fails within the fuzz budget.
+func finishes(mem []int, in []int) bool {
+	c := New("budget", mem)
+	c.SetMemLimit(fuzzMemLimit)
+	c.SetBudget(fuzzBudget)
+	for n := 0; ; {
+		s, err := c.Run()
+		if err != nil {
+			var budget *BudgetError
+			return !errors.As(err, &budget)
+		}
+		switch s {
+		case StateInput:
+			c.In = fuzzInput(in, n)
+			n++
+		case StateHalted:
+			return true
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package intcode
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// Group runs machines that talk over channels, as RunChan does, together with
+// the functions that feed and read them, and detects when they deadlock.
+//
+// Machines are started with Go and other functions with Func. Values reach
+// the members of a group only through seeds and Endpoint.Send, and leave a
+// machine only through its output, so the group knows which values are in
+// flight. If every member still running waits to receive on a channel that
+// holds none of them and is not closed, nothing can wake them and Wait
+// returns a DeadlockError. Members blocked sending are not watched, so a
+// machine whose output nobody reads any more blocks the group for good.
+type Group struct {
+	mu      sync.Mutex
+	members []*Endpoint
+	pending int
+	closed  map[uintptr]bool
+	done    chan struct{}
+	waited  bool
+	stopped bool
+	err     error
+	wg      sync.WaitGroup
+}
+
+// Endpoint is a member of a Group. Functions started with Func use it to
+// communicate with the machines of the group.
+type Endpoint struct {
+	g       *Group
+	name    string
+	m       Machine
+	waiting bool
+	on      uintptr
+	exited  bool
+}
+
+// NewGroup returns an empty group.
+func NewGroup() *Group {
+	return &Group{closed: map[uintptr]bool{}, done: make(chan struct{})}
+}
+
+// Go runs m in its own goroutine. The seed values are its first inputs; after
+// them it reads input from input. Its output is sent to output, which is
+// closed once m halts or fails.
+func (g *Group) Go(name string, m Machine, input <-chan int, output chan<- int, seed ...int) {
+	e := g.join(name, m)
+	go func() {
+		err := e.drive(input, output, seed)
+		if err != nil {
+			err = fmt.Errorf("%s: %w", name, err)
+		}
+		e.exit(err, output)
+	}()
+}
+
+// Func runs f in its own goroutine as a member of the group. f should return
+// once a receive or send reports false.
+func (g *Group) Func(name string, f func(e *Endpoint) error) {
+	e := g.join(name, nil)
+	go func() {
+		err := f(e)
+		if err != nil {
+			err = fmt.Errorf("%s: %w", name, err)
+		}
+		e.exit(err, nil)
+	}()
+}
+
+// Wait waits for every member to return. It returns the first error from a
+// member or a DeadlockError; in either case the other members are stopped
+// when they next send or receive. Deadlocks are only looked for once Wait is
+// called, so every member must have been started by then.
+func (g *Group) Wait() error {
+	g.mu.Lock()
+	g.waited = true
+	g.checkDeadlock()
+	g.mu.Unlock()
+	g.wg.Wait()
+	return g.err
+}
+
+func (g *Group) join(name string, m Machine) *Endpoint {
+	e := &Endpoint{g: g, name: name, m: m}
+	g.mu.Lock()
+	g.members = append(g.members, e)
+	g.mu.Unlock()
+	g.wg.Add(1)
+	return e
+}
+
+// fail records err and stops the group. It must be called with g.mu held.
+func (g *Group) fail(err error) {
+	if g.err == nil {
+		g.err = err
+	}
+	if !g.stopped {
+		g.stopped = true
+		close(g.done)
+	}
+}
+
+// checkDeadlock stops the group if no member can make progress. It must be
+// called with g.mu held.
+func (g *Group) checkDeadlock() {
+	if !g.waited || g.stopped || g.pending > 0 {
+		return
+	}
+	var stuck []StuckMachine
+	for _, e := range g.members {
+		if e.exited {
+			continue
+		}
+		if !e.waiting || g.closed[e.on] {
+			return
+		}
+		ip := -1
+		if e.m != nil {
+			ip = e.m.OpIP()
+		}
+		stuck = append(stuck, StuckMachine{Name: e.name, IP: ip})
+	}
+	if len(stuck) > 0 {
+		g.fail(&DeadlockError{Stuck: stuck})
+	}
+}
+
+// Send sends v on ch. It reports false if the group was stopped first.
+func (e *Endpoint) Send(ch chan<- int, v int) bool {
+	g := e.g
+	g.mu.Lock()
+	g.pending++
+	g.mu.Unlock()
+	select {
+	case ch <- v:
+		return true
+	case <-g.done:
+		return false
+	}
+}
+
+// Receive receives a value from ch. It reports false if ch was closed or the
+// group was stopped first.
+func (e *Endpoint) Receive(ch <-chan int) (int, bool) {
+	g := e.g
+	g.mu.Lock()
+	e.waiting = true
+	e.on = reflect.ValueOf(ch).Pointer()
+	g.checkDeadlock()
+	g.mu.Unlock()
+
+	select {
+	case v, ok := <-ch:
+		g.mu.Lock()
+		e.waiting = false
+		if ok {
+			g.pending--
+		}
+		g.mu.Unlock()
+		return v, ok
+	case <-g.done:
+		return 0, false
+	}
+}
+
+// drive runs a machine, feeding it the seed values and then its input.
+func (e *Endpoint) drive(input <-chan int, output chan<- int, seed []int) error {
+	for {
+		s, err := e.m.Run()
+		if err != nil {
+			return err
+		}
+
+		switch s {
+		case StateInput:
+			if len(seed) > 0 {
+				e.m.Input(seed[0])
+				seed = seed[1:]
+				continue
+			}
+			v, ok := e.Receive(input)
+			if !ok {
+				if e.stopped() {
+					return nil
+				}
+				return io.ErrUnexpectedEOF
+			}
+			e.m.Input(v)
+		case StateOutput:
+			if !e.Send(output, e.m.Output()) {
+				return nil
+			}
+		case StateHalted:
+			return nil
+		}
+	}
+}
+
+func (e *Endpoint) stopped() bool {
+	e.g.mu.Lock()
+	defer e.g.mu.Unlock()
+	return e.g.stopped
+}
+
+// exit marks the member as returned with err, and closes its output, if any.
+func (e *Endpoint) exit(err error, output chan<- int) {
+	g := e.g
+	g.mu.Lock()
+	e.exited = true
+	if output != nil {
+		g.closed[reflect.ValueOf(output).Pointer()] = true
+	}
+	if err != nil {
+		g.fail(err)
+	}
+	g.checkDeadlock()
+	g.mu.Unlock()
+	if output != nil {
+		close(output)
+	}
+	g.wg.Done()
+}
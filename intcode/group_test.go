@@ -0,0 +1,98 @@
+package intcode
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// adder reads two values and outputs their sum.
+const adder = "3,20,3,21,1,20,21,20,4,20,99"
+
+func TestGroupRun(t *testing.T) {
+	in, out := make(chan int), make(chan int)
+	var sum int
+	g := NewGroup()
+	g.Go("adder", New("adder", mustParse(t, adder)), in, out, 40)
+	g.Func("host", func(e *Endpoint) error {
+		if !e.Send(in, 2) {
+			return errors.New("send failed")
+		}
+		sum, _ = e.Receive(out)
+		if _, ok := e.Receive(out); ok {
+			return errors.New("output not closed after halting")
+		}
+		return nil
+	})
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if sum != 42 {
+		t.Errorf("sum = %d, want 42", sum)
+	}
+}
+
+func TestGroupDeadlock(t *testing.T) {
+	// each machine waits for a second value from the other, which only ever
+	// sends one
+	ab, ba := make(chan int, 1), make(chan int, 1)
+	g := NewGroup()
+	g.Go("a", New("a", mustParse(t, "104,1,3,20,3,21,99")), ba, ab)
+	g.Go("b", New("b", mustParse(t, "3,20,104,2,3,21,99")), ab, ba)
+
+	err := g.Wait()
+	var deadlock *DeadlockError
+	if !errors.As(err, &deadlock) {
+		t.Fatalf("error = %v, want a DeadlockError", err)
+	}
+	want := []StuckMachine{{Name: "a", IP: 4}, {Name: "b", IP: 4}}
+	if !reflect.DeepEqual(deadlock.Stuck, want) {
+		t.Errorf("stuck = %+v, want %+v", deadlock.Stuck, want)
+	}
+}
+
+func TestGroupHostDeadlock(t *testing.T) {
+	// the adder wants a second value, but the host only reads its output
+	out := make(chan int)
+	g := NewGroup()
+	g.Go("adder", New("adder", mustParse(t, adder)), nil, out, 1)
+	g.Func("reader", func(e *Endpoint) error {
+		for {
+			if _, ok := e.Receive(out); !ok {
+				return nil
+			}
+		}
+	})
+
+	err := g.Wait()
+	var deadlock *DeadlockError
+	if !errors.As(err, &deadlock) {
+		t.Fatalf("error = %v, want a DeadlockError", err)
+	}
+	want := []StuckMachine{{Name: "adder", IP: 2}, {Name: "reader", IP: -1}}
+	if !reflect.DeepEqual(deadlock.Stuck, want) {
+		t.Errorf("stuck = %+v, want %+v", deadlock.Stuck, want)
+	}
+	if got, want := err.Error(), "deadlock: all machines waiting for input: adder (ip=2), reader"; got != want {
+		t.Errorf("error %q, want %q", got, want)
+	}
+}
+
+func TestGroupMachineError(t *testing.T) {
+	in, out := make(chan int), make(chan int)
+	g := NewGroup()
+	g.Go("bad", New("bad", mustParse(t, "104,1,42")), in, out)
+	g.Func("host", func(e *Endpoint) error {
+		for {
+			if _, ok := e.Receive(out); !ok {
+				return nil
+			}
+		}
+	})
+
+	err := g.Wait()
+	var illegal *IllegalOpcodeError
+	if !errors.As(err, &illegal) || illegal.IP != 2 {
+		t.Fatalf("error = %v, want an IllegalOpcodeError at ip=2", err)
+	}
+}
@@ -0,0 +1,573 @@
+// Package intcode implements the intcode virtual machine shared by every day
+// that runs an intcode program.
+//
+// A Computer can be driven as a state machine with Run, which returns each
+// time the program needs input, produces output, or halts, or over channels
+// with RunChan. RunContext and RunChanContext can be canceled, and SetBudget
+// bounds the number of instructions a computer may execute.
+package intcode
+
+import (
+	"context"
+	"fmt"
+	"math/bits"
+)
+
+// ctxCheckInterval is how many instructions RunContext executes between
+// checks for cancellation.
+const ctxCheckInterval = 1 << 12
+
+const (
+	OpcodeAdd      = 1
+	OpcodeMultiply = 2
+	OpcodeInput    = 3
+	OpcodeOutput   = 4
+	OpcodeJmpIfT   = 5
+	OpcodeJmpIfF   = 6
+	OpcodeLT       = 7
+	OpcodeEql      = 8
+	OpcodeRelAdj   = 9
+	OpcodeDie      = 99
+	ModePosition   = 0
+	ModeImmediate  = 1
+	ModeRelative   = 2
+
+	StateRunning State = 0
+	StateHalted  State = 1
+	StateInput   State = 2
+	StateOutput  State = 3
+
+	ArithWrap    Arith = 0
+	ArithChecked Arith = 1
+)
+
+// State is the reason a Computer stopped running.
+type State int
+
+func (s State) String() string {
+	switch s {
+	case StateRunning:
+		return "running"
+	case StateHalted:
+		return "halted"
+	case StateInput:
+		return "input"
+	case StateOutput:
+		return "output"
+	}
+	return fmt.Sprintf("state(%d)", int(s))
+}
+
+// Arith selects how a Computer handles arithmetic overflow.
+//
+// ArithWrap, the default, wraps silently like Go's int. ArithChecked stops the
+// program with an *OverflowError when an add or multiply does not fit in an
+// int. For arbitrary precision use a BigComputer.
+type Arith int
+
+// Computer is a single intcode machine.
+//
+// When Run returns StateInput the caller sets In before calling Run again; when
+// it returns StateOutput the produced value is in Out.
+type Computer struct {
+	Name string
+	In   int
+	Out  int
+
+	mem    *memory
+	tp     int
+	ip     int
+	rel    int
+	state  State
+	steps  int
+	arith  Arith
+	opIP   int
+	budget int
+
+	tracer  *Tracer
+	rec     *TraceRecord
+	prof    *Profiler
+	journal *Journal
+	session *Recorder
+	ext     *Registry
+}
+
+// New returns a Computer executing the program image mem. The image is copied
+// into the machine's memory, which grows as the program touches addresses
+// beyond it.
+func New(name string, mem []int) *Computer {
+	return &Computer{
+		Name: name,
+		mem:  newMemory(mem),
+	}
+}
+
+// SetMemLimit caps the number of addressable memory cells. Accesses at or
+// beyond the limit fail with an error instead of growing memory. A limit of
+// zero, the default, means unlimited.
+func (c *Computer) SetMemLimit(n int) {
+	c.mem.limit = n
+}
+
+// State returns the state the computer last stopped in.
+func (c *Computer) State() State { return c.state }
+
+// IP returns the instruction pointer.
+func (c *Computer) IP() int { return c.ip }
+
+// OpIP returns the address of the instruction executed last. While the
+// computer waits for input, that is the input instruction.
+func (c *Computer) OpIP() int { return c.opIP }
+
+// SetArith selects the overflow behavior of add and multiply.
+func (c *Computer) SetArith(a Arith) { c.arith = a }
+
+// SetBudget limits the total number of instructions the computer may execute.
+// Once Steps reaches n, running fails with a BudgetError; raising the budget
+// lets the computer continue. A budget of zero, the default, means unlimited.
+func (c *Computer) SetBudget(n int) { c.budget = n }
+
+// Steps returns the number of instructions executed so far.
+func (c *Computer) Steps() int { return c.steps }
+
+// SetIP moves the instruction pointer.
+func (c *Computer) SetIP(ip int) { c.ip = ip }
+
+// Rel returns the relative base.
+func (c *Computer) Rel() int { return c.rel }
+
+// SetRel sets the relative base.
+func (c *Computer) SetRel(rel int) { c.rel = rel }
+
+// InputTarget returns the address the pending input will be written to. It
+// reports false unless the computer is waiting for input.
+func (c *Computer) InputTarget() (int, bool) {
+	return c.tp, c.state == StateInput
+}
+
+// Input sets In, the value the pending input instruction will store.
+func (c *Computer) Input(v int) { c.In = v }
+
+// Output returns Out, the last value output.
+func (c *Computer) Output() int { return c.Out }
+
+// Peek returns the value at addr. Addresses that cannot be read yield zero.
+func (c *Computer) Peek(addr int) int {
+	v, _ := c.mem.get(addr)
+	return v
+}
+
+// Poke sets the value at addr.
+func (c *Computer) Poke(addr int, v int) error {
+	return c.mem.set(addr, v)
+}
+
+// Run executes until the program requests input, produces output, or halts.
+func (c *Computer) Run() (State, error) {
+	return c.RunContext(context.Background())
+}
+
+// RunContext is like Run, but stops with a CanceledError wrapping ctx.Err()
+// once ctx is done. A canceled computer can be resumed by running it again.
+func (c *Computer) RunContext(ctx context.Context) (State, error) {
+	if err := c.resume(); err != nil {
+		return c.state, c.located(err)
+	}
+	done := ctx.Done()
+	for n := 1; ; n++ {
+		s, err := c.step()
+		if err != nil {
+			return 0, err
+		}
+		if s != StateRunning {
+			return s, nil
+		}
+		if done != nil && n%ctxCheckInterval == 0 {
+			select {
+			case <-done:
+				return c.state, c.located(&CanceledError{Err: ctx.Err()})
+			default:
+			}
+		}
+	}
+}
+
+// Step executes a single instruction.
+func (c *Computer) Step() (State, error) {
+	if err := c.resume(); err != nil {
+		return c.state, c.located(err)
+	}
+	return c.step()
+}
+
+// RunChan runs the program to completion, reading input from input and
+// writing output to output. The output channel is not closed. It waits for
+// input forever if none arrives; run the computer in a Group to have that
+// reported as a deadlock.
+func (c *Computer) RunChan(input <-chan int, output chan<- int) error {
+	return c.RunChanContext(context.Background(), input, output)
+}
+
+// RunChanContext is like RunChan, but stops with a CanceledError wrapping
+// ctx.Err() once ctx is done, including while blocked on either channel.
+func (c *Computer) RunChanContext(ctx context.Context, input <-chan int, output chan<- int) error {
+	for {
+		s, err := c.RunContext(ctx)
+		if err != nil {
+			return err
+		}
+
+		switch s {
+		case StateInput:
+			select {
+			case c.In = <-input:
+			case <-ctx.Done():
+				return c.located(&CanceledError{Err: ctx.Err()})
+			}
+		case StateOutput:
+			select {
+			case output <- c.Out:
+			case <-ctx.Done():
+				return c.located(&CanceledError{Err: ctx.Err()})
+			}
+		case StateHalted:
+			return nil
+		}
+	}
+}
+
+// Copy returns an independent copy of the computer, including its memory.
+func (c *Computer) Copy() *Computer {
+	rv := *c
+	rv.mem = c.mem.copy()
+	rv.rec = nil
+	rv.journal = nil
+	rv.session = nil
+	return &rv
+}
+
+func (c *Computer) resume() error {
+	if c.journal != nil {
+		c.journal.begin(c)
+	}
+	switch c.state {
+	case StateRunning, StateOutput:
+	case StateInput:
+		if err := c.store(c.tp, c.In); err != nil {
+			return err
+		}
+		c.traceInput()
+		c.recordInput()
+		if c.prof != nil {
+			c.prof.input()
+		}
+	default:
+		return &InvalidStateError{State: c.state}
+	}
+	c.state = StateRunning
+	return nil
+}
+
+func (c *Computer) step() (State, error) {
+	if c.budget > 0 && c.steps >= c.budget {
+		c.opIP = c.ip
+		return 0, c.located(&BudgetError{Budget: c.budget})
+	}
+	if c.journal != nil {
+		c.journal.begin(c)
+		defer c.journal.end()
+	}
+	c.traceBegin()
+	c.opIP = c.ip
+	var inst Instruction
+	if c.prof != nil {
+		inst, _ = c.decodeExec(c.ip)
+	}
+	s, err := c.exec()
+	if err != nil {
+		c.rec = nil
+		return 0, c.located(err)
+	}
+	c.steps++
+	c.recordStop(s)
+	if c.prof != nil {
+		c.prof.record(inst, c.ip)
+		if s == StateOutput {
+			c.prof.Outputs++
+		}
+	}
+	return s, nil
+}
+
+func (c *Computer) exec() (State, error) {
+	cmdDesc, err := c.read()
+	if err != nil {
+		return 0, err
+	}
+	opcode, modes := decodeWord(cmdDesc)
+
+	switch opcode {
+	case OpcodeAdd:
+		if err := c.arithmeticImpl(&modes, OpcodeAdd, addOp); err != nil {
+			return 0, err
+		}
+	case OpcodeMultiply:
+		if err := c.arithmeticImpl(&modes, OpcodeMultiply, multOp); err != nil {
+			return 0, err
+		}
+	case OpcodeInput:
+		if err := c.inputImpl(&modes); err != nil {
+			return 0, err
+		}
+		c.state = StateInput
+	case OpcodeOutput:
+		if err := c.outputImpl(&modes); err != nil {
+			return 0, err
+		}
+		c.state = StateOutput
+	case OpcodeJmpIfT:
+		if err := c.jumpImpl(&modes, trueCmp); err != nil {
+			return 0, err
+		}
+	case OpcodeJmpIfF:
+		if err := c.jumpImpl(&modes, falseCmp); err != nil {
+			return 0, err
+		}
+	case OpcodeLT:
+		if err := c.cmpImpl(&modes, ltCmp); err != nil {
+			return 0, err
+		}
+	case OpcodeEql:
+		if err := c.cmpImpl(&modes, eqCmp); err != nil {
+			return 0, err
+		}
+	case OpcodeRelAdj:
+		if err := c.relImpl(&modes); err != nil {
+			return 0, err
+		}
+	case OpcodeDie:
+		c.state = StateHalted
+	default:
+		var op *Op
+		if c.ext != nil {
+			op = c.ext.ops[opcode]
+		}
+		if op == nil {
+			return 0, &IllegalOpcodeError{Opcode: opcode}
+		}
+		if err := c.extImpl(op, &modes); err != nil {
+			return 0, err
+		}
+	}
+
+	if c.rec != nil {
+		n, _, _ := c.ext.spec(opcode)
+		c.traceEnd(opcode, modes[:n])
+	}
+	return c.state, nil
+}
+
+func (c *Computer) jumpImpl(modes *[maxParams]int, cmp func(p int) bool) error {
+	v, err := c.param(modes[0])
+	if err != nil {
+		return err
+	}
+	target, err := c.param(modes[1])
+	if err != nil {
+		return err
+	}
+
+	if cmp(v) {
+		c.ip = target
+	}
+
+	return nil
+}
+
+func (c *Computer) inputImpl(modes *[maxParams]int) error {
+	dest, err := c.outputMode(modes[0])
+	if err != nil {
+		return err
+	}
+	c.tp = dest
+	return nil
+}
+
+func (c *Computer) outputImpl(modes *[maxParams]int) error {
+	v, err := c.param(modes[0])
+	if err != nil {
+		return err
+	}
+	c.Out = v
+	return nil
+}
+
+// param reads the next parameter and resolves it according to mode.
+func (c *Computer) param(mode int) (int, error) {
+	p, err := c.read()
+	if err != nil {
+		return 0, err
+	}
+	var rv int
+	switch mode {
+	case ModePosition:
+		rv, err = c.mem.get(p)
+	case ModeImmediate:
+		rv = p
+	case ModeRelative:
+		rv, err = c.mem.get(c.rel + p)
+	default:
+		return 0, &IllegalModeError{Mode: mode}
+	}
+	if err != nil {
+		return 0, err
+	}
+	c.traceOperand(rv)
+	return rv, nil
+}
+
+func (c *Computer) outputMode(mode int) (int, error) {
+	p, err := c.read()
+	if err != nil {
+		return 0, err
+	}
+	switch mode {
+	case ModePosition:
+		c.traceOperand(p)
+		return p, nil
+	case ModeImmediate:
+		return 0, &WriteToImmediateError{}
+	case ModeRelative:
+		c.traceOperand(p + c.rel)
+		return p + c.rel, nil
+	default:
+		return 0, &IllegalModeError{Mode: mode}
+	}
+}
+
+func (c *Computer) arithmeticImpl(modes *[maxParams]int, opcode int, f func(a, b int) (int, bool)) error {
+	a, err := c.param(modes[0])
+	if err != nil {
+		return err
+	}
+	b, err := c.param(modes[1])
+	if err != nil {
+		return err
+	}
+	dest, err := c.outputMode(modes[2])
+	if err != nil {
+		return err
+	}
+	v, ok := f(a, b)
+	if !ok && c.arith == ArithChecked {
+		return &OverflowError{Op: mnemonics[opcode], A: a, B: b}
+	}
+	return c.store(dest, v)
+}
+
+func (c *Computer) cmpImpl(modes *[maxParams]int, f func(a, b int) bool) error {
+	a, err := c.param(modes[0])
+	if err != nil {
+		return err
+	}
+	b, err := c.param(modes[1])
+	if err != nil {
+		return err
+	}
+	dest, err := c.outputMode(modes[2])
+	if err != nil {
+		return err
+	}
+	if f(a, b) {
+		return c.store(dest, 1)
+	}
+	return c.store(dest, 0)
+}
+
+func (c *Computer) relImpl(modes *[maxParams]int) error {
+	v, err := c.param(modes[0])
+	if err != nil {
+		return err
+	}
+	c.rel += v
+	return nil
+}
+
+func (c *Computer) store(addr int, v int) error {
+	if c.journal != nil {
+		c.journal.write(c, addr)
+	}
+	if err := c.mem.set(addr, v); err != nil {
+		return err
+	}
+	c.traceWrite(addr, v)
+	return nil
+}
+
+func (c *Computer) read() (int, error) {
+	rv, err := c.mem.get(c.ip)
+	if err != nil {
+		return 0, err
+	}
+	c.ip++
+	return rv, nil
+}
+
+const minInt = -1 << (bits.UintSize - 1)
+
+// addOp and multOp also report whether the result fit in an int.
+func addOp(a, b int) (int, bool) {
+	s := a + b
+	return s, (s > a) == (b > 0)
+}
+
+func multOp(a, b int) (int, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	p := a * b
+	if (a == -1 && b == minInt) || (b == -1 && a == minInt) {
+		return p, false
+	}
+	return p, p/b == a
+}
+
+func trueCmp(a int) bool      { return a != 0 }
+func falseCmp(a int) bool     { return a == 0 }
+func ltCmp(a int, b int) bool { return a < b }
+func eqCmp(a int, b int) bool { return a == b }
+
+// decodeWord splits an instruction word like ParseOpcode, without allocating.
+// Modes beyond the first three are ignored.
+func decodeWord(code int) (opcode int, modes [maxParams]int) {
+	opcode = code % 100
+	rest := code / 100
+	for i := range modes {
+		modes[i] = rest % 10
+		rest /= 10
+	}
+	return opcode, modes
+}
+
+// ParseOpcode splits an instruction word into its opcode and parameter modes,
+// least significant parameter first.
+func ParseOpcode(code int) (opcode int, modes []int) {
+	opcode = code % 100
+	rest := code / 100
+
+	modes = make([]int, 0)
+	for rest > 0 {
+		mode := rest % 10
+		modes = append(modes, mode)
+		rest /= 10
+	}
+
+	return opcode, modes
+}
+
+func pad(m []int, sz int) []int {
+	if len(m) >= sz {
+		return m
+	}
+	return append(m, make([]int, sz-len(m))...)
+}
@@ -0,0 +1,266 @@
+package intcode
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultJournalInterval is the number of instructions between checkpoints
+// when NewJournal is given an interval of zero.
+const DefaultJournalInterval = 1 << 14
+
+// ErrNoHistory is returned when stepping back to a point the journal does not
+// cover, or when the computer has no journal.
+var ErrNoHistory = errors.New("no earlier state recorded")
+
+// Journal records a computer's execution so that it can be run backward.
+//
+// Each instruction is logged with the registers it started from and the old
+// value of every cell it wrote, so recent instructions are undone directly.
+// Every interval instructions the log is replaced by a checkpoint, a copy of
+// the whole machine. Stepping back past the log restores the nearest earlier
+// checkpoint and executes forward from it, supplying the inputs the program
+// was given the first time. Only the newest keep checkpoints are retained, so
+// memory use is bounded by keep copies of the machine plus one interval of
+// log; history before the oldest checkpoint is forgotten.
+//
+// Only changes made by running the computer are recorded. Poke, SetIP and
+// SetRel on a journaled computer make its history inconsistent.
+type Journal struct {
+	interval    int
+	keep        int
+	undo        []undoEntry
+	open        bool
+	checkpoints []checkpoint
+	// inputs holds the value supplied to each input request, by the step
+	// count at which it was stored.
+	inputs map[int]int
+}
+
+// regs is the part of a computer's state that is not memory.
+type regs struct {
+	ip    int
+	rel   int
+	tp    int
+	opIP  int
+	steps int
+	in    int
+	out   int
+	state State
+}
+
+// undoEntry undoes one instruction, including the pending input stored
+// before it. Only extension opcodes write more than one cell.
+type undoEntry struct {
+	regs
+	n      int
+	writes [maxParams + 1]undoWrite
+}
+
+type undoWrite struct {
+	addr int
+	old  int
+}
+
+type checkpoint struct {
+	regs
+	mem *memory
+}
+
+// NewJournal returns a journal that checkpoints every interval instructions
+// and keeps at most keep checkpoints. An interval of zero selects
+// DefaultJournalInterval; a keep of zero keeps every checkpoint.
+func NewJournal(interval int, keep int) *Journal {
+	if interval <= 0 {
+		interval = DefaultJournalInterval
+	}
+	return &Journal{interval: interval, keep: keep}
+}
+
+// SetJournal starts recording the computer's execution in j, discarding
+// anything j recorded before. The computer cannot step back before this
+// point. A nil journal stops recording.
+func (c *Computer) SetJournal(j *Journal) {
+	c.journal = j
+	if j == nil {
+		return
+	}
+	j.undo = j.undo[:0]
+	j.open = false
+	j.checkpoints = j.checkpoints[:0]
+	j.inputs = map[int]int{}
+	j.checkpoint(c)
+}
+
+// Oldest returns the earliest step count the journal can return to.
+func (j *Journal) Oldest() int {
+	return j.checkpoints[0].steps
+}
+
+// Back undoes the last instruction executed.
+func (c *Computer) Back() error {
+	return c.BackTo(c.steps - 1)
+}
+
+// BackInput returns the computer to the most recent input request it has
+// answered, leaving it waiting for that input again.
+func (c *Computer) BackInput() error {
+	j := c.journal
+	if j == nil {
+		return ErrNoHistory
+	}
+	target := -1
+	for step := range j.inputs {
+		if step > target && (step < c.steps || (step == c.steps && c.state != StateInput)) {
+			target = step
+		}
+	}
+	if target < 0 {
+		return ErrNoHistory
+	}
+	return c.BackTo(target)
+}
+
+// BackTo returns the computer to the point where it had executed step
+// instructions.
+func (c *Computer) BackTo(step int) error {
+	// instructions executed again on the way are not part of the session
+	rec := c.session
+	c.session = nil
+	err := c.backTo(step)
+	c.session = rec
+	if err == nil {
+		c.recordBack()
+	}
+	return err
+}
+
+func (c *Computer) backTo(step int) error {
+	j := c.journal
+	if j == nil || step < j.Oldest() {
+		return ErrNoHistory
+	}
+	if step > c.steps {
+		return fmt.Errorf("step %d is ahead of the computer at step %d", step, c.steps)
+	}
+	j.end()
+
+	if step < c.steps && (len(j.undo) == 0 || j.undo[0].steps > step) {
+		i := len(j.checkpoints) - 1
+		for j.checkpoints[i].steps > step {
+			i--
+		}
+		j.checkpoints = j.checkpoints[:i+1]
+		j.undo = j.undo[:0]
+		cp := j.checkpoints[i]
+		c.mem = cp.mem.copy()
+		c.setRegs(cp.regs)
+		if err := j.replay(c, step); err != nil {
+			return err
+		}
+		j.end()
+	}
+
+	for len(j.undo) > 0 && j.undo[len(j.undo)-1].steps >= step {
+		e := &j.undo[len(j.undo)-1]
+		for i := e.n - 1; i >= 0; i-- {
+			c.mem.set(e.writes[i].addr, e.writes[i].old)
+		}
+		c.setRegs(e.regs)
+		j.undo = j.undo[:len(j.undo)-1]
+	}
+
+	for s := range j.inputs {
+		if s >= step {
+			delete(j.inputs, s)
+		}
+	}
+	return nil
+}
+
+// replay executes forward to step, answering input requests from the
+// recorded inputs.
+func (j *Journal) replay(c *Computer, step int) error {
+	for c.steps < step {
+		if c.state == StateInput {
+			v, ok := j.inputs[c.steps]
+			if !ok {
+				return fmt.Errorf("no input recorded at step %d", c.steps)
+			}
+			c.In = v
+		}
+		if _, err := c.Step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// begin opens an undo entry for the instruction about to run, unless one is
+// already open, checkpointing first if the log is full.
+func (j *Journal) begin(c *Computer) {
+	if j.open {
+		return
+	}
+	if len(j.undo) >= j.interval {
+		j.undo = j.undo[:0]
+		j.checkpoint(c)
+	}
+	if c.state == StateInput {
+		j.inputs[c.steps] = c.In
+	}
+	j.undo = append(j.undo, undoEntry{regs: c.regs()})
+	j.open = true
+}
+
+func (j *Journal) end() {
+	j.open = false
+}
+
+// write records the value at addr before the open instruction overwrites it.
+func (j *Journal) write(c *Computer, addr int) {
+	old, err := c.mem.get(addr)
+	if err != nil || !j.open {
+		return
+	}
+	e := &j.undo[len(j.undo)-1]
+	e.writes[e.n] = undoWrite{addr: addr, old: old}
+	e.n++
+}
+
+func (j *Journal) checkpoint(c *Computer) {
+	j.checkpoints = append(j.checkpoints, checkpoint{regs: c.regs(), mem: c.mem.copy()})
+	if j.keep <= 0 || len(j.checkpoints) <= j.keep {
+		return
+	}
+	j.checkpoints = append(j.checkpoints[:0], j.checkpoints[len(j.checkpoints)-j.keep:]...)
+	for s := range j.inputs {
+		if s < j.Oldest() {
+			delete(j.inputs, s)
+		}
+	}
+}
+
+func (c *Computer) regs() regs {
+	return regs{
+		ip:    c.ip,
+		rel:   c.rel,
+		tp:    c.tp,
+		opIP:  c.opIP,
+		steps: c.steps,
+		in:    c.In,
+		out:   c.Out,
+		state: c.state,
+	}
+}
+
+func (c *Computer) setRegs(r regs) {
+	c.ip = r.ip
+	c.rel = r.rel
+	c.tp = r.tp
+	c.opIP = r.opIP
+	c.steps = r.steps
+	c.In = r.in
+	c.Out = r.out
+	c.state = r.state
+}
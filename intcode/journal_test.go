@@ -0,0 +1,157 @@
+package intcode
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// summer reads values forever, outputting the running total after each one
+// and moving the relative base along.
+const summer = `
+loop:
+	in [x]
+	add [sum], [x], [sum]
+	out [sum]
+	arb #1
+	jt #1, #loop
+x:   .data 0
+sum: .data 0`
+
+// journaled runs summer for n instructions under a journal, answering input
+// requests with the step count. It returns the computer and a snapshot taken
+// after every step, indexed by step count.
+func journaled(t *testing.T, interval int, keep int, n int) (*Computer, []*Snapshot) {
+	t.Helper()
+	mem, err := Assemble(summer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New("journal", mem)
+	c.SetJournal(NewJournal(interval, keep))
+	states := []*Snapshot{c.Snapshot()}
+	for c.Steps() < n {
+		if c.State() == StateInput {
+			c.In = c.Steps()
+		}
+		if _, err := c.Step(); err != nil {
+			t.Fatal(err)
+		}
+		states = append(states, c.Snapshot())
+	}
+	return c, states
+}
+
+// sameState reports whether two snapshots of summer agree. In is the caller's
+// answer to a pending input request rather than machine state, so it is
+// ignored while the machine waits for input.
+func sameState(got *Snapshot, want *Snapshot) bool {
+	g, w := *got, *want
+	if g.State == StateInput {
+		g.In, w.In = 0, 0
+	}
+	return reflect.DeepEqual(g, w)
+}
+
+func TestJournalBackTo(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval int
+		keep     int
+		run      int
+		// targets are stepped back to in order
+		targets []int
+	}{
+		{name: "undo-log", interval: 100, run: 40, targets: []int{39, 31, 30, 2, 0}},
+		{name: "one-checkpoint-back", interval: 16, run: 40, targets: []int{33, 31}},
+		{name: "across-checkpoints", interval: 7, run: 60, targets: []int{59, 43, 42, 13, 6, 0}},
+		{name: "on-checkpoints", interval: 10, run: 50, targets: []int{50, 40, 30, 20, 10, 0}},
+		{name: "kept", interval: 5, keep: 3, run: 40, targets: []int{37, 31, 30}},
+		{name: "every-step", interval: 1, run: 20, targets: []int{19, 18, 10, 1}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, states := journaled(t, tc.interval, tc.keep, tc.run)
+			for _, target := range tc.targets {
+				if err := c.BackTo(target); err != nil {
+					t.Fatalf("BackTo(%d): %v", target, err)
+				}
+				if got := c.Snapshot(); !sameState(got, states[target]) {
+					t.Fatalf("BackTo(%d) gives %+v, want %+v", target, got, states[target])
+				}
+			}
+		})
+	}
+}
+
+func TestJournalBack(t *testing.T) {
+	c, states := journaled(t, 6, 0, 30)
+	for step := 29; step >= 0; step-- {
+		if err := c.Back(); err != nil {
+			t.Fatalf("Back to %d: %v", step, err)
+		}
+		if got := c.Snapshot(); !sameState(got, states[step]) {
+			t.Fatalf("Back to %d gives %+v, want %+v", step, got, states[step])
+		}
+	}
+	if err := c.Back(); !errors.Is(err, ErrNoHistory) {
+		t.Errorf("Back at step 0: error %v, want %v", err, ErrNoHistory)
+	}
+}
+
+func TestJournalForgets(t *testing.T) {
+	c, _ := journaled(t, 5, 2, 40)
+	oldest := c.journal.Oldest()
+	if oldest != 30 {
+		t.Fatalf("oldest step %d, want 30", oldest)
+	}
+	if err := c.BackTo(oldest - 1); !errors.Is(err, ErrNoHistory) {
+		t.Errorf("BackTo(%d): error %v, want %v", oldest-1, err, ErrNoHistory)
+	}
+	if err := c.BackTo(41); err == nil {
+		t.Error("BackTo a step ahead of the computer succeeded")
+	}
+}
+
+func TestJournalRerun(t *testing.T) {
+	// running forward again after stepping back repeats history, and the
+	// journal keeps working for the new run
+	c, states := journaled(t, 7, 0, 60)
+	if err := c.BackTo(20); err != nil {
+		t.Fatal(err)
+	}
+	for c.Steps() < 60 {
+		if c.State() == StateInput {
+			c.In = c.Steps()
+		}
+		if _, err := c.Step(); err != nil {
+			t.Fatal(err)
+		}
+		if got := c.Snapshot(); !sameState(got, states[c.Steps()]) {
+			t.Fatalf("rerun at step %d gives %+v, want %+v", c.Steps(), got, states[c.Steps()])
+		}
+	}
+	if err := c.BackTo(15); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Snapshot(); !sameState(got, states[15]) {
+		t.Errorf("BackTo(15) after rerun gives %+v, want %+v", got, states[15])
+	}
+}
+
+func TestJournalBackInput(t *testing.T) {
+	c, states := journaled(t, 4, 0, 23)
+	// input requests return at steps 1, 6, 11, 16 and 21; the one at 21 has
+	// been answered by the step that followed it
+	for _, want := range []int{21, 16, 11} {
+		if err := c.BackInput(); err != nil {
+			t.Fatalf("BackInput to %d: %v", want, err)
+		}
+		if c.Steps() != want || c.State() != StateInput {
+			t.Fatalf("BackInput: step %d in %s, want step %d waiting for input", c.Steps(), c.State(), want)
+		}
+		if got := c.Snapshot(); !sameState(got, states[want]) {
+			t.Fatalf("BackInput to %d gives %+v, want %+v", want, got, states[want])
+		}
+	}
+}
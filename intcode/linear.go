@@ -0,0 +1,268 @@
+package intcode
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxLinearSteps bounds symbolic evaluation, which cannot otherwise tell a
+// long computation from an infinite loop.
+const maxLinearSteps = 1 << 20
+
+// ErrNotLinear is wrapped by the errors EvalLinear returns when a program
+// cannot be evaluated as a linear function of its unknowns.
+var ErrNotLinear = errors.New("not linear in the unknowns")
+
+// Linear is a linear expression over unknown memory cells: Const plus the sum
+// of each unknown's initial value times its coefficient. Unknowns are named by
+// their address.
+type Linear struct {
+	Const int
+	Coef  map[int]int
+
+	// opaque marks a value read through an address that depends on the
+	// unknowns. It is harmless unless it is used.
+	opaque bool
+}
+
+// IsConst reports whether the expression does not depend on any unknown.
+func (l Linear) IsConst() bool {
+	return !l.opaque && len(l.Coef) == 0
+}
+
+// Eval returns the value of the expression for the given unknowns.
+func (l Linear) Eval(vals map[int]int) int {
+	v := l.Const
+	for addr, k := range l.Coef {
+		v += k * vals[addr]
+	}
+	return v
+}
+
+func (l Linear) String() string {
+	if l.opaque {
+		return "?"
+	}
+	addrs := make([]int, 0, len(l.Coef))
+	for addr := range l.Coef {
+		addrs = append(addrs, addr)
+	}
+	sort.Ints(addrs)
+	terms := make([]string, 0, len(addrs)+1)
+	for _, addr := range addrs {
+		terms = append(terms, fmt.Sprintf("%d*[%d]", l.Coef[addr], addr))
+	}
+	if l.Const != 0 || len(terms) == 0 {
+		terms = append(terms, fmt.Sprint(l.Const))
+	}
+	return strings.Join(terms, " + ")
+}
+
+func (l Linear) add(o Linear) Linear {
+	if l.opaque || o.opaque {
+		return Linear{opaque: true}
+	}
+	rv := Linear{Const: l.Const + o.Const}
+	for _, m := range []map[int]int{l.Coef, o.Coef} {
+		for addr, k := range m {
+			if rv.Coef == nil {
+				rv.Coef = map[int]int{}
+			}
+			if rv.Coef[addr] += k; rv.Coef[addr] == 0 {
+				delete(rv.Coef, addr)
+			}
+		}
+	}
+	return rv
+}
+
+func (l Linear) scale(k int) Linear {
+	rv := Linear{Const: l.Const * k}
+	if k == 0 {
+		return rv
+	}
+	if l.opaque {
+		return Linear{opaque: true}
+	}
+	for addr, c := range l.Coef {
+		if rv.Coef == nil {
+			rv.Coef = map[int]int{}
+		}
+		rv.Coef[addr] = c * k
+	}
+	return rv
+}
+
+// EvalLinear runs mem to completion with the cells at unknowns treated as
+// variables, and returns the final value at addr as a linear expression over
+// them.
+//
+// Evaluation only succeeds when control flow, addresses, comparisons and at
+// least one factor of every multiplication are independent of the unknowns,
+// as in the day 2 program; otherwise the error wraps ErrNotLinear. Values read
+// through an address that depends on the unknowns are allowed as long as they
+// never influence the result. Programs that read input are not supported.
+func EvalLinear(mem []int, unknowns []int, addr int) (Linear, error) {
+	e := &linearEval{mem: map[int]Linear{}}
+	for a, v := range mem {
+		if v != 0 {
+			e.mem[a] = Linear{Const: v}
+		}
+	}
+	for _, a := range unknowns {
+		e.mem[a] = Linear{Coef: map[int]int{a: 1}}
+	}
+
+	for steps := 0; ; steps++ {
+		if steps == maxLinearSteps {
+			return Linear{}, fmt.Errorf("no halt after %d instructions", steps)
+		}
+		ip := e.ip
+		halted, err := e.step()
+		if err != nil {
+			return Linear{}, fmt.Errorf("ip=%d: %w", ip, err)
+		}
+		if halted {
+			if rv := e.mem[addr]; !rv.opaque {
+				return rv, nil
+			}
+			return Linear{}, fmt.Errorf("result read through unknown address: %w", ErrNotLinear)
+		}
+	}
+}
+
+type linearEval struct {
+	mem map[int]Linear
+	ip  int
+	rel int
+}
+
+func (e *linearEval) step() (bool, error) {
+	code := e.mem[e.ip]
+	if !code.IsConst() {
+		return false, fmt.Errorf("instruction depends on unknowns: %w", ErrNotLinear)
+	}
+	opcode, modes := decodeWord(code.Const)
+	n, ok := paramCounts[opcode]
+	if !ok {
+		return false, &IllegalOpcodeError{Opcode: opcode}
+	}
+	inst := linearInst{opcode: opcode, modes: modes[:n], params: make([]Linear, n)}
+	for j := range inst.params {
+		inst.params[j] = e.mem[e.ip+1+j]
+	}
+	e.ip += n + 1
+
+	switch inst.opcode {
+	case OpcodeAdd, OpcodeMultiply, OpcodeLT, OpcodeEql:
+		a, err := e.param(inst, 0)
+		if err != nil {
+			return false, err
+		}
+		b, err := e.param(inst, 1)
+		if err != nil {
+			return false, err
+		}
+		dest, err := e.dest(inst, 2)
+		if err != nil {
+			return false, err
+		}
+		var v Linear
+		switch {
+		case inst.opcode == OpcodeAdd:
+			v = a.add(b)
+		case inst.opcode == OpcodeMultiply && a.IsConst():
+			v = b.scale(a.Const)
+		case inst.opcode == OpcodeMultiply && b.IsConst():
+			v = a.scale(b.Const)
+		case inst.opcode == OpcodeMultiply:
+			return false, fmt.Errorf("product of unknowns %s and %s: %w", a, b, ErrNotLinear)
+		case !a.IsConst() || !b.IsConst():
+			return false, fmt.Errorf("comparison of unknowns %s and %s: %w", a, b, ErrNotLinear)
+		case inst.opcode == OpcodeLT && a.Const < b.Const, inst.opcode == OpcodeEql && a.Const == b.Const:
+			v = Linear{Const: 1}
+		}
+		e.mem[dest] = v
+	case OpcodeJmpIfT, OpcodeJmpIfF:
+		cond, err := e.param(inst, 0)
+		if err != nil {
+			return false, err
+		}
+		target, err := e.param(inst, 1)
+		if err != nil {
+			return false, err
+		}
+		if !cond.IsConst() || !target.IsConst() {
+			return false, fmt.Errorf("jump depends on unknowns: %w", ErrNotLinear)
+		}
+		if (cond.Const != 0) == (inst.opcode == OpcodeJmpIfT) {
+			e.ip = target.Const
+		}
+	case OpcodeRelAdj:
+		v, err := e.param(inst, 0)
+		if err != nil {
+			return false, err
+		}
+		if !v.IsConst() {
+			return false, fmt.Errorf("relative base depends on unknowns: %w", ErrNotLinear)
+		}
+		e.rel += v.Const
+	case OpcodeInput, OpcodeOutput:
+		return false, fmt.Errorf("%s is not supported", mnemonics[inst.opcode])
+	case OpcodeDie:
+		return true, nil
+	}
+	return false, nil
+}
+
+type linearInst struct {
+	opcode int
+	modes  []int
+	params []Linear
+}
+
+func (e *linearEval) param(inst linearInst, j int) (Linear, error) {
+	p := inst.params[j]
+	switch inst.modes[j] {
+	case ModeImmediate:
+		return p, nil
+	case ModePosition, ModeRelative:
+	default:
+		return Linear{}, &IllegalModeError{Mode: inst.modes[j]}
+	}
+	if !p.IsConst() {
+		return Linear{opaque: true}, nil
+	}
+	addr := p.Const
+	if inst.modes[j] == ModeRelative {
+		addr += e.rel
+	}
+	if addr < 0 {
+		return Linear{}, &InvalidAddressError{Addr: addr}
+	}
+	return e.mem[addr], nil
+}
+
+func (e *linearEval) dest(inst linearInst, j int) (int, error) {
+	p := inst.params[j]
+	switch inst.modes[j] {
+	case ModePosition, ModeRelative:
+	case ModeImmediate:
+		return 0, &WriteToImmediateError{}
+	default:
+		return 0, &IllegalModeError{Mode: inst.modes[j]}
+	}
+	if !p.IsConst() {
+		return 0, fmt.Errorf("write through unknown address: %w", ErrNotLinear)
+	}
+	addr := p.Const
+	if inst.modes[j] == ModeRelative {
+		addr += e.rel
+	}
+	if addr < 0 {
+		return 0, &InvalidAddressError{Addr: addr}
+	}
+	return addr, nil
+}
@@ -0,0 +1,62 @@
+package intcode
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEvalLinearDay2(t *testing.T) {
+	mem, err := Load("../2/input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expr, err := EvalLinear(mem, []int{1, 2}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expr.IsConst() || expr.Coef[2] != 1 {
+		t.Fatalf("[0] = %s, want a coefficient of 1 on the verb", expr)
+	}
+
+	// the verb is what is left over once the noun's multiple is taken away
+	const target = 19690720
+	noun := (target - expr.Const) / expr.Coef[1]
+	verb := target - expr.Const - noun*expr.Coef[1]
+	if noun != 93 || verb != 42 {
+		t.Fatalf("noun %d, verb %d, want 93 and 42", noun, verb)
+	}
+
+	// the expression agrees with running the program
+	for _, vals := range [][2]int{{noun, verb}, {12, 2}, {0, 99}} {
+		c := New("gravity", mem)
+		c.Poke(1, vals[0])
+		c.Poke(2, vals[1])
+		if _, err := c.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := expr.Eval(map[int]int{1: vals[0], 2: vals[1]}), c.Peek(0); got != want {
+			t.Errorf("[0] = %d for %v, program leaves %d", got, vals, want)
+		}
+	}
+}
+
+func TestEvalLinearErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		prog     string
+		unknowns []int
+	}{
+		{"product-of-reads", "2,1,2,0,99", []int{1, 2}},
+		{"product", "2,7,8,0,99,0,0,3,4", []int{7, 8}},
+		{"jump-on-unknown", "1005,7,4,99,99,0,0,0", []int{7}},
+		{"write-through-unknown", "9,7,21101,1,1,0,99,5", []int{7}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, err := EvalLinear(mustParse(t, tc.prog), tc.unknowns, 0)
+			if !errors.Is(err, ErrNotLinear) {
+				t.Errorf("EvalLinear = %s, %v, want an error wrapping %v", expr, err, ErrNotLinear)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package intcode
+
+import (
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// Parse parses a comma separated intcode program.
+func Parse(s string) ([]int, error) {
+	words := strings.Split(strings.TrimSpace(s), ",")
+	mem := make([]int, len(words))
+	for i, w := range words {
+		v, err := strconv.Atoi(strings.TrimSpace(w))
+		if err != nil {
+			return nil, err
+		}
+		mem[i] = v
+	}
+	return mem, nil
+}
+
+// Load reads and parses the intcode program in the named file.
+func Load(path string) ([]int, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(string(b))
+}
@@ -0,0 +1,161 @@
+package intcode
+
+import "sort"
+
+const (
+	pageBits = 10
+	pageSize = 1 << pageBits
+	pageMask = pageSize - 1
+)
+
+// maxDensePages bounds the directly indexed page table; pages beyond it live
+// in a map.
+const maxDensePages = 1 << 16
+
+type page [pageSize]int
+
+// memory is a sparse, paged address space. Pages are allocated on first write;
+// reads from unallocated pages return zero. Low pages, where programs keep
+// their code, data and stack, are found through a slice; the rest through a
+// map.
+type memory struct {
+	dense []*page
+	far   map[int]*page
+	limit int
+}
+
+func newMemory(image []int) *memory {
+	m := &memory{
+		far: map[int]*page{},
+	}
+	for i := 0; i < len(image); i += pageSize {
+		p := m.page(i>>pageBits, true)
+		copy(p[:], image[i:])
+	}
+	return m
+}
+
+func (m *memory) page(idx int, alloc bool) *page {
+	if idx < len(m.dense) {
+		if p := m.dense[idx]; p != nil || !alloc {
+			return p
+		}
+	}
+	if idx >= maxDensePages {
+		p, ok := m.far[idx]
+		if !ok && alloc {
+			p = &page{}
+			m.far[idx] = p
+		}
+		return p
+	}
+	if !alloc {
+		return nil
+	}
+	for idx >= len(m.dense) {
+		m.dense = append(m.dense, nil)
+	}
+	p := &page{}
+	m.dense[idx] = p
+	return p
+}
+
+// each calls f for every allocated page.
+func (m *memory) each(f func(idx int, p *page)) {
+	for idx, p := range m.dense {
+		if p != nil {
+			f(idx, p)
+		}
+	}
+	for idx, p := range m.far {
+		f(idx, p)
+	}
+}
+
+func (m *memory) check(addr int) error {
+	if addr < 0 || (m.limit > 0 && addr >= m.limit) {
+		return &InvalidAddressError{Addr: addr, Limit: m.limit}
+	}
+	return nil
+}
+
+func (m *memory) get(addr int) (int, error) {
+	// fast path, small enough to be inlined
+	if idx := addr >> pageBits; addr >= 0 && idx < len(m.dense) && m.limit == 0 {
+		if p := m.dense[idx]; p != nil {
+			return p[addr&pageMask], nil
+		}
+	}
+	return m.getSlow(addr)
+}
+
+func (m *memory) getSlow(addr int) (int, error) {
+	if err := m.check(addr); err != nil {
+		return 0, err
+	}
+	p := m.page(addr>>pageBits, false)
+	if p == nil {
+		return 0, nil
+	}
+	return p[addr&pageMask], nil
+}
+
+func (m *memory) set(addr int, v int) error {
+	if err := m.check(addr); err != nil {
+		return err
+	}
+	m.page(addr>>pageBits, true)[addr&pageMask] = v
+	return nil
+}
+
+func (m *memory) copy() *memory {
+	rv := &memory{
+		dense: make([]*page, len(m.dense)),
+		far:   make(map[int]*page, len(m.far)),
+		limit: m.limit,
+	}
+	for idx, p := range m.dense {
+		if p != nil {
+			np := *p
+			rv.dense[idx] = &np
+		}
+	}
+	for idx, p := range m.far {
+		np := *p
+		rv.far[idx] = &np
+	}
+	return rv
+}
+
+// segment is a contiguous run of memory.
+type segment struct {
+	Addr  int   `json:"addr"`
+	Words []int `json:"words"`
+}
+
+// segments returns the allocated memory as runs of adjacent pages in address
+// order.
+func (m *memory) segments() []segment {
+	pages := map[int]*page{}
+	idxs := []int{}
+	m.each(func(idx int, p *page) {
+		pages[idx] = p
+		idxs = append(idxs, idx)
+	})
+	sort.Ints(idxs)
+
+	var rv []segment
+	for i, idx := range idxs {
+		p := pages[idx]
+		if i > 0 && idxs[i-1] == idx-1 {
+			last := &rv[len(rv)-1]
+			last.Words = append(last.Words, p[:]...)
+			continue
+		}
+		rv = append(rv, segment{
+			Addr:  idx << pageBits,
+			Words: append([]int(nil), p[:]...),
+		})
+	}
+	return rv
+}
@@ -0,0 +1,59 @@
+package intcode
+
+// Native gives programs compiled to Go direct access to a Computer's state.
+// It exists for code generated by Compile and is not needed otherwise.
+type Native struct {
+	c *Computer
+}
+
+// Native returns the computer's native access handle.
+func (c *Computer) Native() Native {
+	return Native{c: c}
+}
+
+// Plain reports whether the computer can run compiled code. Tracing,
+// profiling, checked arithmetic, instruction budgets, journaling, session
+// recording and extension opcodes are only implemented by the interpreter.
+func (n Native) Plain() bool {
+	c := n.c
+	return c.tracer == nil && c.prof == nil && c.arith == ArithWrap && c.budget == 0 &&
+		c.journal == nil && c.session == nil && c.ext == nil
+}
+
+// Resume prepares the computer to continue like Run does, storing pending
+// input.
+func (n Native) Resume() error {
+	if err := n.c.resume(); err != nil {
+		return n.c.located(err)
+	}
+	return nil
+}
+
+// Regs returns the instruction pointer and relative base.
+func (n Native) Regs() (ip int, rel int) {
+	return n.c.ip, n.c.rel
+}
+
+// Load returns the value at addr, reporting false if addr is invalid.
+func (n Native) Load(addr int) (int, bool) {
+	v, err := n.c.mem.get(addr)
+	return v, err == nil
+}
+
+// Store sets the value at addr, reporting false if addr is invalid.
+func (n Native) Store(addr int, v int) bool {
+	return n.c.mem.set(addr, v) == nil
+}
+
+// Yield records where compiled code stopped: the state, the address of the
+// instruction it stopped at, registers, input target for StateInput, and the
+// number of instructions it executed.
+func (n Native) Yield(s State, opIP int, ip int, rel int, tp int, steps int) {
+	c := n.c
+	c.state = s
+	c.opIP = opIP
+	c.ip = ip
+	c.rel = rel
+	c.tp = tp
+	c.steps += steps
+}
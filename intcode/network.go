@@ -0,0 +1,271 @@
+package intcode
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Machine is a machine a Network can drive: a Computer or a compiled program.
+type Machine interface {
+	Run() (State, error)
+	OpIP() int
+	Input(v int)
+	Output() int
+}
+
+// Network runs a group of machines connected by directed links.
+//
+// Machines are declared with Add and connected with Link. Links may form
+// cycles, and a machine may have any number of incoming and outgoing links:
+// every output is copied to each outgoing link, and incoming values are
+// merged into the machine's input in arrival order, after its seed values.
+// Inputs are queued without limit, so a machine is never blocked sending.
+//
+// If every machine still running is waiting for input and none is queued,
+// the network can make no progress and Run fails with a DeadlockError.
+type Network struct {
+	nodes  []*netNode
+	byName map[string]*netNode
+	links  []*Link
+	err    error
+
+	mu      sync.Mutex
+	wake    *sync.Cond
+	stopped bool
+}
+
+type netNode struct {
+	name    string
+	m       Machine
+	out     []*Link
+	queue   []int
+	waiting bool
+	halted  bool
+	ip      int
+}
+
+// Link is a directed connection between two machines. It records the values
+// passed along it, so results can be read once the network has run.
+type Link struct {
+	From string
+	To   string
+
+	mu    sync.Mutex
+	last  int
+	count int
+	to    *netNode
+}
+
+// DeadlockError reports a network in which every machine still running is
+// waiting for input that can never arrive.
+type DeadlockError struct {
+	Stuck []StuckMachine
+}
+
+// StuckMachine identifies a machine blocked on input by name and the address
+// of the input instruction it is blocked at. For a function in a Group, IP is
+// -1.
+type StuckMachine struct {
+	Name string
+	IP   int
+}
+
+func (e *DeadlockError) Error() string {
+	stuck := make([]string, len(e.Stuck))
+	for i, s := range e.Stuck {
+		stuck[i] = s.Name
+		if s.IP >= 0 {
+			stuck[i] = fmt.Sprintf("%s (ip=%d)", s.Name, s.IP)
+		}
+	}
+	return "deadlock: all machines waiting for input: " + strings.Join(stuck, ", ")
+}
+
+// NewNetwork returns an empty network.
+func NewNetwork() *Network {
+	n := &Network{byName: map[string]*netNode{}}
+	n.wake = sync.NewCond(&n.mu)
+	return n
+}
+
+// Add declares a machine named name. The seed values are its first inputs,
+// such as a phase setting.
+func (n *Network) Add(name string, m Machine, seed ...int) {
+	if _, ok := n.byName[name]; ok {
+		n.fail(fmt.Errorf("duplicate machine %q", name))
+		return
+	}
+	nd := &netNode{name: name, m: m, queue: append([]int(nil), seed...)}
+	n.nodes = append(n.nodes, nd)
+	n.byName[name] = nd
+}
+
+// Link connects the output of from to the input of to. Both machines must
+// already have been added.
+func (n *Network) Link(from string, to string) *Link {
+	l := &Link{From: from, To: to}
+	src, ok := n.byName[from]
+	if !ok {
+		n.fail(fmt.Errorf("link from unknown machine %q", from))
+		return l
+	}
+	if l.to, ok = n.byName[to]; !ok {
+		n.fail(fmt.Errorf("link to unknown machine %q", to))
+		return l
+	}
+	src.out = append(src.out, l)
+	n.links = append(n.links, l)
+	return l
+}
+
+func (n *Network) fail(err error) {
+	if n.err == nil {
+		n.err = err
+	}
+}
+
+// Last returns the last value passed along the link, and false if there was
+// none.
+func (l *Link) Last() (int, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.last, l.count > 0
+}
+
+// Count returns the number of values passed along the link.
+func (l *Link) Count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.count
+}
+
+func (l *Link) record(v int) {
+	l.mu.Lock()
+	l.last = v
+	l.count++
+	l.mu.Unlock()
+}
+
+// Run runs every machine in its own goroutine until all of them have halted.
+// It returns the first declaration error, the first error from a machine, or
+// a DeadlockError; in either of the latter cases the other machines are
+// stopped when they next wait for input. A network can only be run once.
+func (n *Network) Run() error {
+	if n.err != nil {
+		return n.err
+	}
+
+	errs := make(chan error, len(n.nodes))
+	for _, nd := range n.nodes {
+		go func(nd *netNode) {
+			err := n.drive(nd)
+			if err != nil {
+				err = fmt.Errorf("%s: %w", nd.name, err)
+			}
+			errs <- err
+		}(nd)
+	}
+
+	var first error
+	for range n.nodes {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+			n.stop()
+		}
+	}
+	if first == nil {
+		first = n.err
+	}
+	return first
+}
+
+// drive runs a single machine, delivering its output and waiting for its
+// input.
+func (n *Network) drive(nd *netNode) error {
+	for {
+		s, err := nd.m.Run()
+		if err != nil {
+			return err
+		}
+
+		switch s {
+		case StateInput:
+			v, ok := n.receive(nd)
+			if !ok {
+				return nil
+			}
+			nd.m.Input(v)
+		case StateOutput:
+			n.send(nd, nd.m.Output())
+		case StateHalted:
+			n.mu.Lock()
+			nd.halted = true
+			n.checkDeadlock()
+			n.mu.Unlock()
+			return nil
+		}
+	}
+}
+
+func (n *Network) send(nd *netNode, v int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, l := range nd.out {
+		l.record(v)
+		l.to.queue = append(l.to.queue, v)
+	}
+	n.wake.Broadcast()
+}
+
+// receive waits for the next input to nd. It reports false if the network was
+// stopped first.
+func (n *Network) receive(nd *netNode) (int, bool) {
+	ip := nd.m.OpIP()
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	nd.waiting = true
+	nd.ip = ip
+	n.checkDeadlock()
+	for len(nd.queue) == 0 && !n.stopped {
+		n.wake.Wait()
+	}
+	nd.waiting = false
+	if len(nd.queue) == 0 {
+		return 0, false
+	}
+	v := nd.queue[0]
+	nd.queue = nd.queue[1:]
+	return v, true
+}
+
+// checkDeadlock stops the network if no machine can make progress. It must be
+// called with n.mu held.
+func (n *Network) checkDeadlock() {
+	if n.stopped {
+		return
+	}
+	var stuck []StuckMachine
+	for _, nd := range n.nodes {
+		if nd.halted {
+			continue
+		}
+		if !nd.waiting || len(nd.queue) > 0 {
+			return
+		}
+		stuck = append(stuck, StuckMachine{Name: nd.name, IP: nd.ip})
+	}
+	if len(stuck) > 0 {
+		n.fail(&DeadlockError{Stuck: stuck})
+		n.stopped = true
+		n.wake.Broadcast()
+	}
+}
+
+func (n *Network) stop() {
+	n.mu.Lock()
+	n.stopped = true
+	n.wake.Broadcast()
+	n.mu.Unlock()
+}
@@ -0,0 +1,52 @@
+package intcode
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func mustParse(t *testing.T, prog string) []int {
+	t.Helper()
+	mem, err := Parse(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mem
+}
+
+func TestNetworkMachineError(t *testing.T) {
+	n := NewNetwork()
+	n.Add("ok", New("ok", mustParse(t, "3,20,99")))
+	n.Add("bad", New("bad", mustParse(t, "104,1,42")))
+	n.Link("bad", "ok")
+
+	err := n.Run()
+	var illegal *IllegalOpcodeError
+	if !errors.As(err, &illegal) {
+		t.Fatalf("error = %v, want an IllegalOpcodeError", err)
+	}
+	if illegal.Opcode != 42 || illegal.IP != 2 {
+		t.Errorf("opcode %d at ip=%d, want 42 at ip=2", illegal.Opcode, illegal.IP)
+	}
+}
+
+func TestNetworkDeadlock(t *testing.T) {
+	// each machine waits for a second value from the other, which only ever
+	// sends one
+	n := NewNetwork()
+	n.Add("a", New("a", mustParse(t, "104,1,3,20,3,21,99")))
+	n.Add("b", New("b", mustParse(t, "3,20,104,2,3,21,99")))
+	n.Link("a", "b")
+	n.Link("b", "a")
+
+	err := n.Run()
+	var deadlock *DeadlockError
+	if !errors.As(err, &deadlock) {
+		t.Fatalf("error = %v, want a DeadlockError", err)
+	}
+	want := []StuckMachine{{Name: "a", IP: 4}, {Name: "b", IP: 4}}
+	if !reflect.DeepEqual(deadlock.Stuck, want) {
+		t.Errorf("stuck = %+v, want %+v", deadlock.Stuck, want)
+	}
+}
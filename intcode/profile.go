@@ -0,0 +1,204 @@
+package intcode
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Profiler collects execution statistics from a Computer.
+//
+// Besides flat counts it keeps a shadow call stack so time can be attributed
+// to subroutines. Intcode has no call instruction; a call is recognized as an
+// immediate return address pushed onto the relative-base stack directly
+// followed by a jump, and a return as a jump back to that address.
+type Profiler struct {
+	Total   int
+	Addrs   map[int]int
+	Opcodes map[int]int
+	Inputs  int
+	Outputs int
+
+	// InputGaps and InputSteps hold the wall time and instruction count
+	// between consecutive inputs.
+	InputGaps  []time.Duration
+	InputSteps []int
+
+	stacks    map[string]int
+	frames    []profFrame
+	key       string
+	pushed    int
+	pushedAt  int
+	lastInput time.Time
+	lastStep  int
+}
+
+type profFrame struct {
+	entry int
+	ret   int
+}
+
+// NewProfiler returns an empty Profiler.
+func NewProfiler() *Profiler {
+	return &Profiler{
+		Addrs:    map[int]int{},
+		Opcodes:  map[int]int{},
+		stacks:   map[string]int{},
+		key:      "main",
+		pushedAt: -1,
+	}
+}
+
+// SetProfiler attaches a profiler to the computer. A nil profiler disables
+// profiling.
+func (c *Computer) SetProfiler(p *Profiler) {
+	c.prof = p
+}
+
+// decodeExec decodes the instruction at addr the way exec runs it. Unlike
+// Decode it accepts mode digits on parameters the opcode lacks, so that every
+// executed instruction is profiled and reported under its own opcode. It
+// reports false for an unknown opcode.
+func (c *Computer) decodeExec(addr int) (Instruction, bool) {
+	code, _ := c.mem.get(addr)
+	opcode, modes := decodeWord(code)
+	n, name, ok := c.ext.spec(opcode)
+	if !ok {
+		return Instruction{}, false
+	}
+	inst := Instruction{
+		Addr:   addr,
+		Opcode: opcode,
+		Modes:  append([]int(nil), modes[:n]...),
+		Params: make([]int, n),
+	}
+	for i := range inst.Params {
+		inst.Params[i], _ = c.mem.get(addr + 1 + i)
+	}
+	if _, builtin := paramCounts[opcode]; !builtin {
+		inst.name = name
+	}
+	return inst, true
+}
+
+func (p *Profiler) record(inst Instruction, next int) {
+	p.Addrs[inst.Addr]++
+	p.Opcodes[inst.Opcode]++
+	p.stacks[p.key]++
+
+	fallthru := inst.Addr + inst.Len()
+	if v, ok := inst.pushedConst(); ok {
+		p.pushed = v
+		p.pushedAt = p.Total
+	}
+	if inst.Jump() && next != fallthru {
+		if p.pushedAt == p.Total-1 && p.pushed == fallthru {
+			p.frames = append(p.frames, profFrame{entry: next, ret: fallthru})
+			p.key += fmt.Sprintf(";sub_%d", next)
+		} else if n := len(p.frames); n > 0 && p.frames[n-1].ret == next {
+			p.frames = p.frames[:n-1]
+			p.key = p.key[:strings.LastIndexByte(p.key, ';')]
+		}
+	}
+	p.Total++
+}
+
+func (p *Profiler) input() {
+	now := time.Now()
+	if p.Inputs > 0 {
+		p.InputGaps = append(p.InputGaps, now.Sub(p.lastInput))
+		p.InputSteps = append(p.InputSteps, p.Total-p.lastStep)
+	}
+	p.Inputs++
+	p.lastInput = now
+	p.lastStep = p.Total
+}
+
+// Report writes a summary of the profile and the top hottest addresses,
+// disassembled from c's memory.
+func (p *Profiler) Report(w io.Writer, c *Computer, top int) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "instructions: %d\n", p.Total)
+	fmt.Fprintf(bw, "inputs: %d outputs: %d\n", p.Inputs, p.Outputs)
+	if len(p.InputSteps) > 0 {
+		var steps int
+		var gap time.Duration
+		minSteps, maxSteps := p.InputSteps[0], p.InputSteps[0]
+		for i, s := range p.InputSteps {
+			steps += s
+			gap += p.InputGaps[i]
+			if s < minSteps {
+				minSteps = s
+			}
+			if s > maxSteps {
+				maxSteps = s
+			}
+		}
+		n := len(p.InputSteps)
+		fmt.Fprintf(bw, "between inputs: %d instructions avg (min %d, max %d), %s avg\n",
+			steps/n, minSteps, maxSteps, gap/time.Duration(n))
+	}
+
+	fmt.Fprintln(bw, "\nopcodes:")
+	for _, op := range sortedByCount(p.Opcodes) {
+		n := p.Opcodes[op]
+		_, name, _ := c.ext.spec(op)
+		fmt.Fprintf(bw, "  %-4s %12d %6.2f%%\n", name, n, percent(n, p.Total))
+	}
+
+	fmt.Fprintln(bw, "\nhot spots:")
+	addrs := sortedByCount(p.Addrs)
+	if top > 0 && len(addrs) > top {
+		addrs = addrs[:top]
+	}
+	for _, addr := range addrs {
+		n := p.Addrs[addr]
+		text := "?"
+		if inst, ok := c.decodeExec(addr); ok {
+			text = inst.String()
+		}
+		fmt.Fprintf(bw, "  %6d %12d %6.2f%%  %s\n", addr, n, percent(n, p.Total), text)
+	}
+
+	return bw.Flush()
+}
+
+// WriteFolded writes the instruction counts per call stack in the folded
+// format understood by flamegraph.pl and speedscope.
+func (p *Profiler) WriteFolded(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	keys := make([]string, 0, len(p.stacks))
+	for k := range p.stacks {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(bw, "%s %d\n", k, p.stacks[k])
+	}
+	return bw.Flush()
+}
+
+func sortedByCount(m map[int]int) []int {
+	rv := make([]int, 0, len(m))
+	for k := range m {
+		rv = append(rv, k)
+	}
+	sort.Slice(rv, func(i, j int) bool {
+		if m[rv[i]] != m[rv[j]] {
+			return m[rv[i]] > m[rv[j]]
+		}
+		return rv[i] < rv[j]
+	})
+	return rv
+}
+
+func percent(n int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(n) / float64(total)
+}
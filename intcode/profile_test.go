@@ -0,0 +1,76 @@
+package intcode
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// profiled runs prog to completion under a profiler.
+func profiled(t *testing.T, mem []int) *Profiler {
+	t.Helper()
+	p := NewProfiler()
+	c := New("profile", mem)
+	c.SetProfiler(p)
+	for {
+		s, err := c.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s == StateHalted {
+			return p
+		}
+	}
+}
+
+func TestProfilerCounts(t *testing.T) {
+	// out and jt carry mode digits for parameters they do not have, which
+	// the interpreter ignores
+	p := profiled(t, []int{1104, 7, 1105, 0, 0, 99})
+	if want := map[int]int{0: 1, 2: 1, 5: 1}; !reflect.DeepEqual(p.Addrs, want) {
+		t.Errorf("addresses %v, want %v", p.Addrs, want)
+	}
+	want := map[int]int{OpcodeOutput: 1, OpcodeJmpIfT: 1, OpcodeDie: 1}
+	if !reflect.DeepEqual(p.Opcodes, want) {
+		t.Errorf("opcodes %v, want %v", p.Opcodes, want)
+	}
+	if p.Total != 3 || p.Outputs != 1 {
+		t.Errorf("%d instructions and %d outputs, want 3 and 1", p.Total, p.Outputs)
+	}
+}
+
+func TestProfilerCalls(t *testing.T) {
+	mem, err := Assemble(`
+		add #ret, #0, rb+0
+		jt #1, #sub
+	ret: out #1
+		hlt
+	sub: jt #1, rb+0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := profiled(t, mem)
+	var b bytes.Buffer
+	if err := p.WriteFolded(&b); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "main 4\nmain;sub_10 1\n"; got != want {
+		t.Errorf("folded stacks\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestProfilerReport(t *testing.T) {
+	mem := []int{1104, 7, 1105, 0, 0, 99}
+	p := profiled(t, mem)
+	var b bytes.Buffer
+	if err := p.Report(&b, New("profile", mem), 10); err != nil {
+		t.Fatal(err)
+	}
+	report := b.String()
+	for _, want := range []string{"instructions: 3\n", "  out ", "  jt ", "out #7\n", "hlt\n"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report lacks %q:\n%s", want, report)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package intcode
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// FindFirst tries candidates 0 to n-1 on GOMAXPROCS workers and returns one
+// for which try reports true. cand builds candidate i; the workers call it as
+// they take up each index, so candidates are never all in memory at once. try
+// is called concurrently, each time with a fresh copy of base to run. Work
+// stops as soon as a candidate matches or try fails, so when several match it
+// is not specified which is returned.
+func FindFirst(base *Computer, n int, cand func(i int) []int, try func(c *Computer, cand []int) (bool, error)) ([]int, bool, error) {
+	var (
+		mu    sync.Mutex
+		found []int
+	)
+	err := pool(n, func(i int) (bool, error) {
+		vals := cand(i)
+		ok, err := try(base.Copy(), vals)
+		if err != nil || !ok {
+			return false, err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if found == nil {
+			found = vals
+		}
+		return true, nil
+	})
+	return found, found != nil, err
+}
+
+// FindMax scores candidates 0 to n-1 on GOMAXPROCS workers and returns the one
+// with the highest score; ties go to the earliest candidate. cand and score
+// are called like cand and try in FindFirst. If progress is not nil it is
+// called, one call at a time, with each new best so far. Work stops at the
+// first error.
+func FindMax(base *Computer, n int, cand func(i int) []int, score func(c *Computer, cand []int) (int, error), progress func(cand []int, score int)) ([]int, int, error) {
+	var (
+		mu       sync.Mutex
+		best     = -1
+		bestCand []int
+		max      int
+	)
+	err := pool(n, func(i int) (bool, error) {
+		vals := cand(i)
+		v, err := score(base.Copy(), vals)
+		if err != nil {
+			return false, err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if best < 0 || v > max || (v == max && i < best) {
+			best, bestCand, max = i, vals, v
+			if progress != nil {
+				progress(vals, v)
+			}
+		}
+		return false, nil
+	})
+	if best < 0 {
+		return nil, 0, err
+	}
+	return bestCand, max, err
+}
+
+// Product describes every combination of values from lo[i] to hi[i]
+// inclusive, varying the last position fastest, for FindFirst and FindMax. It
+// returns the number of combinations and a function building combination i.
+func Product(lo []int, hi []int) (int, func(i int) []int) {
+	n := 1
+	for i := range lo {
+		if hi[i] < lo[i] {
+			return 0, nil
+		}
+		n *= hi[i] - lo[i] + 1
+	}
+	return n, func(i int) []int {
+		rv := make([]int, len(lo))
+		for j := len(rv) - 1; j >= 0; j-- {
+			size := hi[j] - lo[j] + 1
+			rv[j] = lo[j] + i%size
+			i /= size
+		}
+		return rv
+	}
+}
+
+// pool calls f with each index below n on GOMAXPROCS workers until f asks to
+// stop, fails, or the indexes run out. It returns the first error.
+func pool(n int, f func(i int) (stop bool, err error)) error {
+	var (
+		next    int64 = -1
+		stopped int32
+		once    sync.Once
+		first   error
+		wg      sync.WaitGroup
+	)
+	workers := runtime.GOMAXPROCS(0)
+	if workers > n {
+		workers = n
+	}
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt32(&stopped) == 0 {
+				i := atomic.AddInt64(&next, 1)
+				if i >= int64(n) {
+					return
+				}
+				stop, err := f(int(i))
+				if err != nil {
+					once.Do(func() { first = err })
+				}
+				if stop || err != nil {
+					atomic.StoreInt32(&stopped, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return first
+}
@@ -0,0 +1,185 @@
+package intcode
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// permutations returns every ordering of vals.
+func permutations(vals []int) [][]int {
+	if len(vals) <= 1 {
+		return [][]int{append([]int(nil), vals...)}
+	}
+	var rv [][]int
+	for i := range vals {
+		rest := append(append([]int(nil), vals[:i]...), vals[i+1:]...)
+		for _, p := range permutations(rest) {
+			rv = append(rv, append([]int{vals[i]}, p...))
+		}
+	}
+	return rv
+}
+
+// amplify runs a loop of amplifiers copied from base with the given phases and
+// returns the last signal fed back to the first, as in day 7.
+func amplify(base *Computer, phases []int) (int, error) {
+	n := NewNetwork()
+	for i, phase := range phases {
+		seed := []int{phase}
+		if i == 0 {
+			seed = append(seed, 0)
+		}
+		n.Add(fmt.Sprint(i), base.Copy(), seed...)
+	}
+	var feedback *Link
+	for i := range phases {
+		feedback = n.Link(fmt.Sprint(i), fmt.Sprint((i+1)%len(phases)))
+	}
+	if err := n.Run(); err != nil {
+		return 0, err
+	}
+	v, _ := feedback.Last()
+	return v, nil
+}
+
+func TestFindMaxAmplifiers(t *testing.T) {
+	tests := []struct {
+		prog   string
+		phases []int
+		want   []int
+		signal int
+	}{
+		{"3,15,3,16,1002,16,10,16,1,16,15,15,4,15,99,0,0",
+			[]int{0, 1, 2, 3, 4}, []int{4, 3, 2, 1, 0}, 43210},
+		{"3,23,3,24,1002,24,10,24,1002,23,-1,23,101,5,23,23,1,24,23,23,4,23,99,0,0",
+			[]int{0, 1, 2, 3, 4}, []int{0, 1, 2, 3, 4}, 54321},
+		{"3,26,1001,26,-4,26,3,27,1002,27,2,27,1,27,26,27,4,27,1001,28,-1,28,1005,28,6,99,0,0,5",
+			[]int{5, 6, 7, 8, 9}, []int{9, 8, 7, 6, 5}, 139629729},
+		{"3,52,1001,52,-5,52,3,53,1,52,56,54,1007,54,5,55,1005,55,26,1001,54,-5,54,1105,1,12,1,53,54,53,1008,54,0,55,1001,55,1,55,2,53,55,53,4,53,1001,56,-1,56,1005,56,6,99,0,0,0,0,10",
+			[]int{5, 6, 7, 8, 9}, []int{9, 7, 8, 5, 6}, 18216},
+	}
+	for _, tc := range tests {
+		t.Run(fmt.Sprint(tc.signal), func(t *testing.T) {
+			perms := permutations(tc.phases)
+			cand := func(i int) []int { return perms[i] }
+			got, signal, err := FindMax(New("amp", mustParse(t, tc.prog)), len(perms), cand, amplify, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if signal != tc.signal || !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("max signal %d from %v, want %d from %v", signal, got, tc.signal, tc.want)
+			}
+		})
+	}
+}
+
+func TestFindMaxTies(t *testing.T) {
+	// candidates 2k and 2k+1 share score k, so the best score is shared by
+	// the last two and goes to the earlier one
+	const n = 100
+	cand := func(i int) []int { return []int{i} }
+	score := func(c *Computer, cand []int) (int, error) { return cand[0] / 2, nil }
+
+	var inside int32
+	var bests [][2]int
+	progress := func(cand []int, score int) {
+		if atomic.AddInt32(&inside, 1) != 1 {
+			t.Error("progress called concurrently")
+		}
+		bests = append(bests, [2]int{cand[0], score})
+		atomic.AddInt32(&inside, -1)
+	}
+	got, max, err := FindMax(New("tie", []int{99}), n, cand, score, progress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if max != n/2-1 || !reflect.DeepEqual(got, []int{n - 2}) {
+		t.Errorf("best %v with %d, want [%d] with %d", got, max, n-2, n/2-1)
+	}
+
+	// each best so far beats the one before, or ties it earlier
+	for i := 1; i < len(bests); i++ {
+		prev, cur := bests[i-1], bests[i]
+		if cur[1] < prev[1] || (cur[1] == prev[1] && cur[0] > prev[0]) {
+			t.Errorf("progress went from %v to %v", prev, cur)
+		}
+	}
+	if last := bests[len(bests)-1]; last != [2]int{n - 2, n/2 - 1} {
+		t.Errorf("last progress %v, want the result", last)
+	}
+}
+
+func TestFindFirstStops(t *testing.T) {
+	const n = 1 << 30
+	cand := func(i int) []int { return []int{i} }
+
+	var calls int64
+	got, ok, err := FindFirst(New("first", []int{99}), n, cand, func(c *Computer, cand []int) (bool, error) {
+		atomic.AddInt64(&calls, 1)
+		return cand[0] == 1000, nil
+	})
+	if err != nil || !ok || !reflect.DeepEqual(got, []int{1000}) {
+		t.Fatalf("FindFirst = %v, %t, %v, want [1000]", got, ok, err)
+	}
+	if calls > 100000 {
+		t.Errorf("%d candidates tried after a match at 1000", calls)
+	}
+
+	calls = 0
+	boom := errors.New("boom")
+	_, ok, err = FindFirst(New("first", []int{99}), n, cand, func(c *Computer, cand []int) (bool, error) {
+		atomic.AddInt64(&calls, 1)
+		if cand[0] == 1000 {
+			return false, boom
+		}
+		return false, nil
+	})
+	if !errors.Is(err, boom) || ok {
+		t.Fatalf("FindFirst = %t, %v, want error %v", ok, err, boom)
+	}
+	if calls > 100000 {
+		t.Errorf("%d candidates tried after an error at 1000", calls)
+	}
+}
+
+func TestFindFirstCopies(t *testing.T) {
+	// every candidate writes itself into its computer and reads it back;
+	// shared computers would mix them up
+	mem := mustParse(t, "4,3,99,0")
+	base := New("copies", mem)
+	const n = 10000
+	cand := func(i int) []int { return []int{i} }
+
+	var mu sync.Mutex
+	seen := map[*Computer]bool{}
+	_, ok, err := FindFirst(base, n, cand, func(c *Computer, cand []int) (bool, error) {
+		mu.Lock()
+		if c == base || seen[c] {
+			mu.Unlock()
+			return false, errors.New("computer reused")
+		}
+		seen[c] = true
+		mu.Unlock()
+
+		if err := c.Poke(3, cand[0]); err != nil {
+			return false, err
+		}
+		if s, err := c.Run(); err != nil || s != StateOutput {
+			return false, fmt.Errorf("run: %s, %v", s, err)
+		}
+		if c.Out != cand[0] {
+			return false, fmt.Errorf("candidate %d read back %d", cand[0], c.Out)
+		}
+		return false, nil
+	})
+	if err != nil || ok {
+		t.Fatalf("FindFirst = %t, %v", ok, err)
+	}
+	if base.Peek(3) != 0 || base.State() != StateRunning {
+		t.Errorf("base changed: mem[3] = %d, state %s", base.Peek(3), base.State())
+	}
+}
@@ -0,0 +1,179 @@
+package intcode
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// SessionEvent is one line of a recorded session: an input stored, an output
+// produced, the program halting, or the computer stepping back. Step is the
+// number of instructions executed when it happened, or for Back the step
+// count returned to.
+type SessionEvent struct {
+	Step int  `json:"step"`
+	In   *int `json:"in,omitempty"`
+	Out  *int `json:"out,omitempty"`
+	Halt bool `json:"halt,omitempty"`
+	Back bool `json:"back,omitempty"`
+}
+
+func (e SessionEvent) String() string {
+	switch {
+	case e.In != nil:
+		return fmt.Sprintf("input %d at step %d", *e.In, e.Step)
+	case e.Out != nil:
+		return fmt.Sprintf("output %d at step %d", *e.Out, e.Step)
+	case e.Halt:
+		return fmt.Sprintf("halt at step %d", e.Step)
+	case e.Back:
+		return fmt.Sprintf("back to step %d", e.Step)
+	}
+	return fmt.Sprintf("input request at step %d", e.Step)
+}
+
+// Recorder writes a computer's session, one JSON event per line, so that it
+// can be replayed with Replay.
+type Recorder struct {
+	enc *json.Encoder
+	err error
+}
+
+// NewRecorder returns a Recorder writing to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w)}
+}
+
+// Err returns the first error encountered writing the session.
+func (r *Recorder) Err() error {
+	return r.err
+}
+
+func (r *Recorder) emit(e SessionEvent) {
+	if r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(e)
+}
+
+// SetRecorder records every input, output, halt and step back of the
+// computer to r. A nil recorder stops recording.
+func (c *Computer) SetRecorder(r *Recorder) {
+	c.session = r
+}
+
+func (c *Computer) recordInput() {
+	if c.session == nil {
+		return
+	}
+	in := c.In
+	c.session.emit(SessionEvent{Step: c.steps, In: &in})
+}
+
+func (c *Computer) recordStop(s State) {
+	if c.session == nil {
+		return
+	}
+	switch s {
+	case StateOutput:
+		out := c.Out
+		c.session.emit(SessionEvent{Step: c.steps, Out: &out})
+	case StateHalted:
+		c.session.emit(SessionEvent{Step: c.steps, Halt: true})
+	}
+}
+
+func (c *Computer) recordBack() {
+	if c.session != nil {
+		c.session.emit(SessionEvent{Step: c.steps, Back: true})
+	}
+}
+
+// ReplayError reports a replayed computer diverging from its recording.
+type ReplayError struct {
+	Fault
+	Want SessionEvent
+	Got  SessionEvent
+}
+
+func (e *ReplayError) Error() string {
+	return fmt.Sprintf("replay diverged: recorded %s, got %s (%s)", e.Want, e.Got, &e.Fault)
+}
+
+// Replay runs c headlessly on a recorded session, supplying the recorded
+// inputs and checking that the outputs and halt match the recording, at the
+// same steps. It returns nil once the session is exhausted, leaving c where
+// the recording ended, or a ReplayError at the first divergence. c must start
+// where the recorded computer did, from the same image or snapshot.
+//
+// If c has no journal, Replay attaches one so that sessions which stepped
+// back can be replayed.
+func Replay(c *Computer, r io.Reader) error {
+	if c.journal == nil {
+		c.SetJournal(NewJournal(0, 0))
+	}
+	dec := json.NewDecoder(r)
+	// stopped is set while c is at a stop that no event has matched yet.
+	stopped := c.state == StateInput || c.state == StateHalted
+	for {
+		var want SessionEvent
+		if err := dec.Decode(&want); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("reading session: %w", err)
+		}
+
+		if want.Back {
+			if err := c.BackTo(want.Step); err != nil {
+				return err
+			}
+			stopped = c.state == StateInput
+			continue
+		}
+
+		if !stopped {
+			if _, err := c.Run(); err != nil {
+				return err
+			}
+			stopped = true
+		}
+		got := SessionEvent{Step: c.steps}
+		switch c.state {
+		case StateOutput:
+			out := c.Out
+			got.Out = &out
+		case StateHalted:
+			got.Halt = true
+		}
+		if !matches(want, got) {
+			return c.located(&ReplayError{Want: want, Got: got})
+		}
+		if c.state == StateInput {
+			c.In = *want.In
+		}
+		stopped = c.state == StateHalted
+	}
+}
+
+// matches reports whether got, an event produced on replay, agrees with the
+// recorded want. Inputs agree with any request at the same step.
+func matches(want SessionEvent, got SessionEvent) bool {
+	if want.Step != got.Step || want.Halt != got.Halt || (want.Out == nil) != (got.Out == nil) {
+		return false
+	}
+	if want.Out != nil && *want.Out != *got.Out {
+		return false
+	}
+	return want.Halt || want.Out != nil || want.In != nil
+}
+
+// ReplayFile replays the session recorded in the file at path.
+func ReplayFile(c *Computer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return Replay(c, f)
+}
@@ -0,0 +1,175 @@
+package intcode
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// totaller outputs the running total of its inputs until it reads a zero.
+const totaller = `
+loop:
+	in [x]
+	eq [x], #0, [done]
+	jt [done], #end
+	add [sum], [x], [sum]
+	out [sum]
+	jt #1, #loop
+end:
+	hlt
+x:    .data 0
+done: .data 0
+sum:  .data 0`
+
+func newTotaller(t *testing.T) *Computer {
+	t.Helper()
+	mem, err := Assemble(totaller)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New("totaller", mem)
+}
+
+// record runs the totaller on in, stepping back to the last input request
+// when an input is -1, and returns the recorded session, the computer and its
+// outputs.
+func record(t *testing.T, in []int) (string, *Computer, []int) {
+	t.Helper()
+	var b bytes.Buffer
+	r := NewRecorder(&b)
+	c := newTotaller(t)
+	c.SetJournal(NewJournal(0, 0))
+	c.SetRecorder(r)
+	var out []int
+	for {
+		s, err := c.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch s {
+		case StateInput:
+			for in[0] < 0 {
+				if err := c.BackInput(); err != nil {
+					t.Fatal(err)
+				}
+				out = out[:len(out)-1]
+				in = in[1:]
+			}
+			c.In, in = in[0], in[1:]
+		case StateOutput:
+			out = append(out, c.Out)
+		case StateHalted:
+			if r.Err() != nil {
+				t.Fatal(r.Err())
+			}
+			return b.String(), c, out
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []int
+		out  []int
+	}{
+		{"straight", []int{3, 4, 5, 0}, []int{3, 7, 12}},
+		{"stepped-back", []int{3, 4, -1, 9, 0}, []int{3, 12}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			session, recorded, out := record(t, tc.in)
+			if !reflect.DeepEqual(out, tc.out) {
+				t.Fatalf("recorded outputs %v, want %v", out, tc.out)
+			}
+			c := newTotaller(t)
+			if err := Replay(c, strings.NewReader(session)); err != nil {
+				t.Fatal(err)
+			}
+			if c.State() != StateHalted || c.Steps() != recorded.Steps() {
+				t.Errorf("replay ended %s at step %d, want halted at step %d", c.State(), c.Steps(), recorded.Steps())
+			}
+			if !reflect.DeepEqual(c.Snapshot().Mem, recorded.Snapshot().Mem) {
+				t.Errorf("replayed memory differs from the recording's")
+			}
+		})
+	}
+}
+
+func TestReplayMismatch(t *testing.T) {
+	session, _, _ := record(t, []int{3, 4, 5, 0})
+
+	// change the second output
+	lines := strings.Split(strings.TrimSpace(session), "\n")
+	var step int
+	var outputs int
+	for i, line := range lines {
+		var e SessionEvent
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatal(err)
+		}
+		if e.Out == nil {
+			continue
+		}
+		if outputs++; outputs == 2 {
+			*e.Out = 8
+			step = e.Step
+			b, _ := json.Marshal(e)
+			lines[i] = string(b)
+		}
+	}
+
+	err := Replay(newTotaller(t), strings.NewReader(strings.Join(lines, "\n")))
+	var replay *ReplayError
+	if !errors.As(err, &replay) {
+		t.Fatalf("error = %v, want a ReplayError", err)
+	}
+	if replay.Want.Step != step || replay.Got.Step != step || replay.Step != step {
+		t.Errorf("diverged at step %d, recorded step %d, got step %d; want %d",
+			replay.Step, replay.Want.Step, replay.Got.Step, step)
+	}
+	if *replay.Want.Out != 8 || replay.Got.Out == nil || *replay.Got.Out != 7 {
+		t.Errorf("recorded %s, got %s, want output 8 and 7", replay.Want, replay.Got)
+	}
+}
+
+func TestReplayDamaged(t *testing.T) {
+	session, _, _ := record(t, []int{3, 4, 5, 0})
+	lines := strings.SplitAfter(session, "\n")
+
+	// a recording cut between events replays as far as it goes
+	c := newTotaller(t)
+	if err := Replay(c, strings.NewReader(strings.Join(lines[:3], ""))); err != nil {
+		t.Fatalf("replaying the first events: %v", err)
+	}
+	var last SessionEvent
+	if err := json.Unmarshal([]byte(lines[2]), &last); err != nil {
+		t.Fatal(err)
+	}
+	if c.Steps() != last.Step || c.State() == StateHalted {
+		t.Errorf("stopped %s at step %d, want step %d", c.State(), c.Steps(), last.Step)
+	}
+
+	// SplitAfter leaves an empty string after the final newline
+	end := lines[len(lines)-2]
+	tests := []struct {
+		name    string
+		session string
+	}{
+		{"truncated", session[:len(session)-len(end)/2]},
+		{"corrupt", lines[0] + "{\"step\": \"x\"}\n" + strings.Join(lines[1:], "")},
+		{"garbage", lines[0] + "not json\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Replay(newTotaller(t), strings.NewReader(tc.session))
+			var replay *ReplayError
+			if err == nil || errors.As(err, &replay) {
+				t.Errorf("error = %v, want a read error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package intcode
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// SnapshotVersion is the snapshot format version written by this package.
+const SnapshotVersion = 2
+
+// Snapshot is the complete state of a Computer in a form that can be stored
+// and restored later, possibly by another process. Tracers, profilers,
+// journals, recorders and extension registries are not part of it and must
+// be attached again to the restored computer.
+type Snapshot struct {
+	Version   int       `json:"version"`
+	Name      string    `json:"name"`
+	IP        int       `json:"ip"`
+	OpIP      int       `json:"opIP"`
+	Rel       int       `json:"rel"`
+	State     State     `json:"state"`
+	InputAddr int       `json:"inputAddr"`
+	In        int       `json:"in"`
+	Out       int       `json:"out"`
+	Steps     int       `json:"steps"`
+	MemLimit  int       `json:"memLimit,omitempty"`
+	Arith     Arith     `json:"arith,omitempty"`
+	Budget    int       `json:"budget,omitempty"`
+	Mem       []segment `json:"mem"`
+
+	// Host holds state kept by the program driving the computer, such as a
+	// game's screen, so that it can be restored along with the computer.
+	// The computer neither sets nor reads it.
+	Host json.RawMessage `json:"host,omitempty"`
+}
+
+// Snapshot captures the computer's current state.
+func (c *Computer) Snapshot() *Snapshot {
+	return &Snapshot{
+		Version:   SnapshotVersion,
+		Name:      c.Name,
+		IP:        c.ip,
+		OpIP:      c.opIP,
+		Rel:       c.rel,
+		State:     c.state,
+		InputAddr: c.tp,
+		In:        c.In,
+		Out:       c.Out,
+		Steps:     c.steps,
+		MemLimit:  c.mem.limit,
+		Arith:     c.arith,
+		Budget:    c.budget,
+		Mem:       c.mem.segments(),
+	}
+}
+
+// Computer returns a new Computer in the snapshot's state.
+func (s *Snapshot) Computer() (*Computer, error) {
+	opIP := s.OpIP
+	switch s.Version {
+	case SnapshotVersion:
+	case 1:
+		// Version 1 did not record the last instruction. Input and output
+		// are the only instructions that stop the computer with its
+		// instruction pointer moved on, both by two words.
+		opIP = s.IP
+		if s.State == StateInput || s.State == StateOutput {
+			opIP = s.IP - 2
+		}
+	default:
+		return nil, fmt.Errorf("unsupported snapshot version %d", s.Version)
+	}
+
+	c := &Computer{
+		Name:   s.Name,
+		In:     s.In,
+		Out:    s.Out,
+		mem:    newMemory(nil),
+		tp:     s.InputAddr,
+		ip:     s.IP,
+		opIP:   opIP,
+		rel:    s.Rel,
+		state:  s.State,
+		steps:  s.Steps,
+		arith:  s.Arith,
+		budget: s.Budget,
+	}
+	for _, seg := range s.Mem {
+		for i, w := range seg.Words {
+			if err := c.mem.set(seg.Addr+i, w); err != nil {
+				return nil, err
+			}
+		}
+	}
+	c.mem.limit = s.MemLimit
+	return c, nil
+}
+
+// Write encodes the snapshot to w.
+func (s *Snapshot) Write(w io.Writer) error {
+	return json.NewEncoder(w).Encode(s)
+}
+
+// ReadSnapshot decodes a snapshot written by Snapshot.Write.
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	var s Snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Save writes the snapshot to the named file.
+func (s *Snapshot) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := s.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// ReadSnapshotFile reads a snapshot from the named file.
+func ReadSnapshotFile(path string) (*Snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadSnapshot(file)
+}
+
+// SaveSnapshot writes a snapshot of c to the named file.
+func SaveSnapshot(path string, c *Computer) error {
+	return c.Snapshot().Save(path)
+}
+
+// LoadSnapshot restores a computer from a snapshot file written by
+// SaveSnapshot.
+func LoadSnapshot(path string) (*Computer, error) {
+	s, err := ReadSnapshotFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return s.Computer()
+}
@@ -0,0 +1,97 @@
+package intcode
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// roundTrip writes s and reads it back as a computer.
+func roundTrip(t *testing.T, s *Snapshot) *Computer {
+	t.Helper()
+	var b bytes.Buffer
+	if err := s.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadSnapshot(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := read.Computer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// stopAtInput runs a program that outputs 1, then stops to read into
+// address 200.
+func stopAtInput(t *testing.T) *Computer {
+	t.Helper()
+	mem, err := Assemble(`
+		out #1
+		in [200]
+		out [200]
+		hlt`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New("snapshot", mem)
+	for _, want := range []State{StateOutput, StateInput} {
+		if s, err := c.Run(); err != nil || s != want {
+			t.Fatalf("Run = %s, %v, want %s", s, err, want)
+		}
+	}
+	return c
+}
+
+func TestSnapshotAtInput(t *testing.T) {
+	c := stopAtInput(t)
+	got := roundTrip(t, c.Snapshot())
+	if !reflect.DeepEqual(got.Snapshot(), c.Snapshot()) {
+		t.Fatalf("restored %+v, want %+v", got.Snapshot(), c.Snapshot())
+	}
+	if got.OpIP() != 2 || got.IP() != 4 {
+		t.Errorf("restored at ip %d after op %d, want ip 4 after the input at 2", got.IP(), got.OpIP())
+	}
+
+	got.In = 9
+	if s, err := got.Run(); err != nil || s != StateOutput || got.Out != 9 {
+		t.Fatalf("Run = %s, %v with output %d, want output 9", s, err, got.Out)
+	}
+	if s, err := got.Run(); err != nil || s != StateHalted {
+		t.Fatalf("Run = %s, %v, want %s", s, err, StateHalted)
+	}
+}
+
+func TestSnapshotInputFault(t *testing.T) {
+	// storing the input fails once the restored computer may not grow to the
+	// input address, and the fault points at the input instruction
+	c := roundTrip(t, stopAtInput(t).Snapshot())
+	c.SetMemLimit(100)
+	_, err := c.Run()
+	f, ok := FaultOf(err)
+	if !ok {
+		t.Fatalf("error = %v, want a fault", err)
+	}
+	if f.IP != 2 {
+		t.Errorf("fault at ip %d, want 2", f.IP)
+	}
+}
+
+func TestSnapshotVersion1(t *testing.T) {
+	s := stopAtInput(t).Snapshot()
+	s.Version, s.OpIP = 1, 0
+	c, err := s.Computer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.OpIP() != 2 {
+		t.Errorf("version 1 snapshot restored after op %d, want 2", c.OpIP())
+	}
+
+	s.Version = SnapshotVersion + 1
+	if _, err := s.Computer(); err == nil {
+		t.Error("snapshot of a later version restored")
+	}
+}
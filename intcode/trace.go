@@ -0,0 +1,143 @@
+package intcode
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// TraceRecord describes one executed instruction.
+//
+// An input instruction is traced twice: when it stops to wait for input,
+// without In, so that a run ending there still shows it, and again once the
+// value has been stored, with In and the write.
+type TraceRecord struct {
+	Step     int          `json:"step"`
+	IP       int          `json:"ip"`
+	Opcode   int          `json:"opcode"`
+	Op       string       `json:"op"`
+	Modes    []int        `json:"modes"`
+	Operands []int        `json:"operands"`
+	Writes   []TraceWrite `json:"writes,omitempty"`
+	Rel      int          `json:"rel"`
+	In       *int         `json:"in,omitempty"`
+	Out      *int         `json:"out,omitempty"`
+}
+
+// TraceWrite is a memory write made by an instruction.
+type TraceWrite struct {
+	Addr  int `json:"addr"`
+	Value int `json:"value"`
+}
+
+// TraceFilter selects which instructions are traced. Zero values leave the
+// corresponding dimension unfiltered; ranges include From and exclude To.
+type TraceFilter struct {
+	AddrFrom int
+	AddrTo   int
+	Opcodes  []int
+	StepFrom int
+	StepTo   int
+}
+
+func (f TraceFilter) match(r *TraceRecord) bool {
+	if r.IP < f.AddrFrom || (f.AddrTo > 0 && r.IP >= f.AddrTo) {
+		return false
+	}
+	if r.Step < f.StepFrom || (f.StepTo > 0 && r.Step >= f.StepTo) {
+		return false
+	}
+	if len(f.Opcodes) == 0 {
+		return true
+	}
+	for _, op := range f.Opcodes {
+		if op == r.Opcode {
+			return true
+		}
+	}
+	return false
+}
+
+// Tracer writes a JSON record per executed instruction, one per line.
+type Tracer struct {
+	Filter TraceFilter
+
+	enc *json.Encoder
+	err error
+}
+
+// NewTracer returns a Tracer writing to w.
+func NewTracer(w io.Writer) *Tracer {
+	return &Tracer{enc: json.NewEncoder(w)}
+}
+
+// Err returns the first error encountered writing the trace.
+func (t *Tracer) Err() error {
+	return t.err
+}
+
+func (t *Tracer) emit(r *TraceRecord) {
+	if t.err != nil || !t.Filter.match(r) {
+		return
+	}
+	t.err = t.enc.Encode(r)
+}
+
+// SetTracer enables tracing of every instruction the computer executes. A nil
+// tracer disables tracing.
+func (c *Computer) SetTracer(t *Tracer) {
+	c.tracer = t
+}
+
+func (c *Computer) traceBegin() {
+	if c.tracer == nil {
+		return
+	}
+	c.rec = &TraceRecord{
+		Step: c.steps,
+		IP:   c.ip,
+		Rel:  c.rel,
+	}
+}
+
+func (c *Computer) traceOperand(v int) {
+	if c.rec != nil {
+		c.rec.Operands = append(c.rec.Operands, v)
+	}
+}
+
+func (c *Computer) traceWrite(addr int, v int) {
+	if c.rec != nil {
+		c.rec.Writes = append(c.rec.Writes, TraceWrite{Addr: addr, Value: v})
+	}
+}
+
+// traceEnd emits the current record. Records for input instructions are
+// emitted as they stop and then held until the input value is supplied.
+func (c *Computer) traceEnd(opcode int, modes []int) {
+	if c.rec == nil {
+		return
+	}
+	c.rec.Opcode = opcode
+	_, c.rec.Op, _ = c.ext.spec(opcode)
+	c.rec.Modes = append([]int(nil), modes...)
+	switch opcode {
+	case OpcodeInput:
+		c.tracer.emit(c.rec)
+		return
+	case OpcodeOutput:
+		out := c.Out
+		c.rec.Out = &out
+	}
+	c.tracer.emit(c.rec)
+	c.rec = nil
+}
+
+func (c *Computer) traceInput() {
+	if c.rec == nil {
+		return
+	}
+	in := c.In
+	c.rec.In = &in
+	c.tracer.emit(c.rec)
+	c.rec = nil
+}
@@ -0,0 +1,100 @@
+package intcode
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// traced decodes the records written to b.
+func traced(t *testing.T, b *bytes.Buffer) []TraceRecord {
+	t.Helper()
+	var recs []TraceRecord
+	dec := json.NewDecoder(b)
+	for dec.More() {
+		var r TraceRecord
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		recs = append(recs, r)
+	}
+	return recs
+}
+
+func TestTraceInput(t *testing.T) {
+	mem, err := Assemble(`
+		add #1, #2, [x]
+		in [x]
+		out [x]
+		hlt
+	x:  .data 0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	c := New("trace", mem)
+	c.SetTracer(NewTracer(&b))
+
+	// a run that ends waiting for input still shows the input instruction
+	if s, err := c.Run(); err != nil || s != StateInput {
+		t.Fatalf("Run = %s, %v, want %s", s, err, StateInput)
+	}
+	got := traced(t, &b)
+	if len(got) != 2 {
+		t.Fatalf("%d records waiting for input, want 2: %+v", len(got), got)
+	}
+	if r := got[1]; r.Op != "in" || r.IP != 4 || r.Step != 1 || r.In != nil || len(r.Writes) != 0 {
+		t.Errorf("record at the input stop = %+v, want in at ip 4 without input", r)
+	}
+
+	// once answered, it is traced again with the value and its write
+	c.In = 7
+	if s, err := c.Run(); err != nil || s != StateOutput {
+		t.Fatalf("Run = %s, %v, want %s", s, err, StateOutput)
+	}
+	got = traced(t, &b)
+	if len(got) != 2 {
+		t.Fatalf("%d records after input, want 2: %+v", len(got), got)
+	}
+	in := got[0]
+	if in.Op != "in" || in.IP != 4 || in.Step != 1 || in.In == nil || *in.In != 7 {
+		t.Errorf("record after input = %+v, want in at ip 4 reading 7", in)
+	}
+	if want := []TraceWrite{{Addr: 9, Value: 7}}; !reflect.DeepEqual(in.Writes, want) {
+		t.Errorf("input writes = %v, want %v", in.Writes, want)
+	}
+	if out := got[1]; out.Op != "out" || out.Out == nil || *out.Out != 7 {
+		t.Errorf("output record = %+v, want out of 7", out)
+	}
+}
+
+func TestTraceFilter(t *testing.T) {
+	mem, err := Assemble(`
+		add #1, #2, [x]
+		out [x]
+		out #5
+		hlt
+	x:  .data 0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	tr := NewTracer(&b)
+	tr.Filter = TraceFilter{Opcodes: []int{OpcodeOutput}, StepFrom: 2}
+	c := New("trace", mem)
+	c.SetTracer(tr)
+	for {
+		s, err := c.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s == StateHalted {
+			break
+		}
+	}
+	got := traced(t, &b)
+	if len(got) != 1 || got[0].Step != 2 || got[0].Out == nil || *got[0].Out != 5 {
+		t.Errorf("filtered trace = %+v, want only the output at step 2", got)
+	}
+}